@@ -1,18 +1,101 @@
 package cmd
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
 )
 
 var compareCmd = &cobra.Command{
-	Use:  "compare",
+	Use:   "compare",
+	Short: "Compare two libraries",
+	Long: `This tool probes two libraries, on the same or on different servers, and reports
+items that are missing on either side as well as items whose quality, codecs,
+size or bit rate differ. Libraries are given as "server/library", where library
+is either the library key or its title.`,
 	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindFlags(cmd, "cdn", "concurrency", "connection", "format", "prefer-local", "quiet", "token")
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("not yet implemented")
+		ctx, cancel := interruptContext()
+
+		defer cancel()
+
+		left, err := probeTarget(ctx, cmd.Flag("left").Value.String())
+
+		if err != nil {
+			return err
+		}
+
+		right, err := probeTarget(ctx, cmd.Flag("right").Value.String())
+
+		if err != nil {
+			return err
+		}
+
+		comparison := plex.Compare(left, right)
+
+		comparison.SetCDN(viper.GetBool("cdn"))
+
+		switch viper.GetString("format") {
+		case "ascii":
+			comparison.Ascii(os.Stdout)
+		case "html":
+			if err := comparison.Html(os.Stdout); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("\"%s\" is not a supported output format", viper.GetString("format"))
+		}
+
+		return nil
 	},
 }
 
+func probeTarget(ctx context.Context, target string) (*plex.Probe, error) {
+	i := strings.LastIndex(target, "/")
+
+	if i < 1 || i == len(target)-1 {
+		return nil, fmt.Errorf("\"%s\" is not a valid target, expected \"server/library\"", target)
+	}
+
+	p, err := connectTo(target[:i])
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := p.ResolveLibraryKey(target[i+1:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	progress := newProgressPrinter()
+
+	progress.attach(p)
+
+	defer progress.done()
+
+	return p.Probe(ctx, key)
+}
+
 func init() {
+	compareCmd.Flags().Bool("cdn", false, "link Bootstrap, jQuery, Popper and Tablesort from their CDNs instead of inlining stand-ins for them")
+	compareCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	compareCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	compareCmd.Flags().String("format", "ascii", "output format")
+	compareCmd.Flags().String("left", "", "left side, as \"server/library\"")
+	compareCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	compareCmd.Flags().Bool("quiet", false, "disable progress reporting on stderr")
+	compareCmd.Flags().String("right", "", "right side, as \"server/library\"")
+	compareCmd.Flags().String("token", "", "Plex access token")
+	_ = compareCmd.MarkFlagRequired("left")
+	_ = compareCmd.MarkFlagRequired("right")
 	rootCmd.AddCommand(compareCmd)
 }
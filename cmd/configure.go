@@ -1,17 +1,64 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var configureCmd = &cobra.Command{
 	Use:   "configure",
-	Short: "",
-	Long:  ``,
-	Args:  cobra.NoArgs,
+	Short: "Configure default settings",
+	Long: `This tool interactively asks for a Plex access token, a default server, a
+default library and a default output format, and saves them to the config file.
+Any other settings already present in the config file are preserved.`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("not yet implemented")
+		token, err := plex.PromptForToken(viper.GetString("token"))
+
+		if err != nil {
+			return err
+		}
+
+		p, err := plex.New(token)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := p.GetServers(); err != nil {
+			return fmt.Errorf("unable to verify access token: %s", err)
+		}
+
+		server, err := p.PromptForServer()
+
+		if err != nil {
+			return err
+		}
+
+		if err := p.UseServer(server); err != nil {
+			return err
+		}
+
+		library, err := p.PromptForLibraryKey()
+
+		if err != nil {
+			return err
+		}
+
+		format, err := plex.PromptForFormat([]string{"ascii", "csv", "html", "json"}, viper.GetString("format"))
+
+		if err != nil {
+			return err
+		}
+
+		return saveConfig(map[string]interface{}{
+			"format":  format,
+			"library": library,
+			"server":  server.Name,
+			"token":   token,
+		})
 	},
 }
 
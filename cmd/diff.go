@@ -1,8 +1,13 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
+	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
 )
 
 var diffCmd = &cobra.Command{
@@ -10,11 +15,91 @@ var diffCmd = &cobra.Command{
 	Short: "Keep track of changes to your media",
 	Long:  `This tool pulls down metadata about your media from Plex Media Server and compares it to the previous run in order to detect and keep track of changes.`,
 	Args:  cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindFlags(cmd, "concurrency", "connection", "library", "prefer-local", "quiet", "server", "snapshots", "token", "url")
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("not yet implemented")
+		dir := viper.GetString("snapshots")
+
+		if dir == "" {
+			home, err := homedir.Dir()
+
+			if err != nil {
+				return err
+			}
+
+			dir = filepath.Join(home, fmt.Sprintf(".%s", appName), "snapshots")
+		}
+
+		p, err := connect()
+
+		if err != nil {
+			return err
+		}
+
+		key, err := libraryKey(p)
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := interruptContext()
+
+		defer cancel()
+
+		progress := newProgressPrinter()
+
+		progress.attach(p)
+
+		probe, err := p.Probe(ctx, key)
+
+		progress.done()
+
+		if err != nil {
+			return err
+		}
+
+		current := probe.Snapshot()
+		path := plex.SnapshotPath(dir, probe.Server(), key)
+		previous, err := plex.LoadSnapshot(path)
+
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if err := current.Save(path); err != nil {
+			return err
+		}
+
+		if previous == nil {
+			_, _ = fmt.Fprintf(os.Stderr, "No previous snapshot found, saved %d items to \"%s\"\n", len(current.Media), path)
+
+			return nil
+		}
+
+		diff := current.Diff(previous)
+
+		if diff.Empty() {
+			_, _ = fmt.Fprintf(os.Stderr, "No changes since %s\n", previous.Created.Local().Format("2006-01-02 15:04:05"))
+
+			return nil
+		}
+
+		diff.Ascii(os.Stdout)
+
+		return nil
 	},
 }
 
 func init() {
+	diffCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	diffCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	diffCmd.Flags().String("library", "", "Plex library key")
+	diffCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	diffCmd.Flags().Bool("quiet", false, "disable progress reporting on stderr")
+	diffCmd.Flags().String("server", "", "Plex server name")
+	diffCmd.Flags().String("snapshots", "", fmt.Sprintf("snapshot directory (default \"$HOME/.%s/snapshots\")", appName))
+	diffCmd.Flags().String("token", "", "Plex access token")
+	diffCmd.Flags().String("url", "", "Plex Media Server URL, bypassing server discovery")
 	rootCmd.AddCommand(diffCmd)
 }
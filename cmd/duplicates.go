@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+)
+
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates",
+	Short: "Find titles stored more than once",
+	Long: `This tool probes one or more libraries and reports items with several versions,
+as well as separate items that resolve to the same movie or episode, either by
+GUID or by title and year, or show, season and episode number. Every copy is
+listed with its quality, size and path, together with the space that would be
+reclaimed by keeping only the best copy.`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindFlags(cmd, "all-libraries", "cdn", "concurrency", "connection", "filter", "format", "library", "prefer-local", "quiet", "server", "token", "url")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := connect()
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := interruptContext()
+
+		defer cancel()
+
+		probe, err := probeLibraries(ctx, p)
+
+		if err != nil {
+			return err
+		}
+
+		probe.SetCDN(viper.GetBool("cdn"))
+
+		duplicates := probe.Duplicates()
+
+		switch viper.GetString("format") {
+		case "ascii":
+			duplicates.Ascii(os.Stdout)
+		case "csv":
+			if err := duplicates.Csv(os.Stdout); err != nil {
+				return err
+			}
+		case "html":
+			if err := duplicates.Html(os.Stdout); err != nil {
+				return err
+			}
+		case "json":
+			if err := duplicates.Json(os.Stdout); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("\"%s\" is not a supported output format", viper.GetString("format"))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	duplicatesCmd.Flags().Bool("all-libraries", false, "probe every library on the server")
+	duplicatesCmd.Flags().Bool("cdn", false, "link Bootstrap, jQuery, Popper and Tablesort from their CDNs instead of inlining stand-ins for them")
+	duplicatesCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	duplicatesCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	duplicatesCmd.Flags().String("filter", "", "only include media matching an expression, e.g. 'quality < 1080p and size > 20GB'")
+	duplicatesCmd.Flags().String("format", "ascii", "output format (ascii, csv, html or json)")
+	duplicatesCmd.Flags().StringSlice("library", []string{}, "Plex library key or title, may be repeated")
+	duplicatesCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	duplicatesCmd.Flags().Bool("quiet", false, "disable progress reporting on stderr")
+	duplicatesCmd.Flags().String("server", "", "Plex server name")
+	duplicatesCmd.Flags().String("token", "", "Plex access token")
+	duplicatesCmd.Flags().String("url", "", "Plex Media Server URL, bypassing server discovery")
+	rootCmd.AddCommand(duplicatesCmd)
+}
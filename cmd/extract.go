@@ -1,8 +1,10 @@
 package cmd
 
 import (
-	"errors"
+	"github.com/jyggen/plex-tools/extract"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
 )
 
 var extractCmd = &cobra.Command{
@@ -10,15 +12,40 @@ var extractCmd = &cobra.Command{
 	Short: "Extract .rar files",
 	Long: `This tool scans the input path and extracts all .rar files found within. In
 order to prevent software that's monitoring the filesystem from picking up files
-that are currently being extracted, the .rar files are extracted to a temporary
-path and then the files within are moved back into the source path once
-extracted.'`,
+that are currently being extracted, the .rar files are extracted to a hidden
+directory next to each archive and then the files within are moved into place
+once extracted.
+
+A --temp path on another filesystem than the input can't be moved from
+atomically, so files are then copied next to their destination under a hidden
+".partial" name first. Extracted archives are recorded in a hidden
+".<archive>.extracted" file, so that later runs skip them without reading them.`,
 	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindFlags(cmd, "delete-archives", "input", "temp")
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("not yet implemented")
+		e, err := extract.New(viper.GetString("temp"), viper.GetBool("delete-archives"), os.Stderr)
+
+		if err != nil {
+			return err
+		}
+
+		input := viper.GetString("input")
+
+		if input == "" {
+			if input, err = os.Getwd(); err != nil {
+				return err
+			}
+		}
+
+		return e.Walk(input)
 	},
 }
 
 func init() {
+	extractCmd.Flags().Bool("delete-archives", false, "delete archives once extracted")
+	extractCmd.Flags().String("input", "", "input path (default current directory)")
+	extractCmd.Flags().String("temp", "", "staging path, preferably on the same filesystem as the input (default a hidden directory next to each archive)")
 	rootCmd.AddCommand(extractCmd)
 }
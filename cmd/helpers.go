@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+func bindFlags(cmd *cobra.Command, names ...string) error {
+	for _, name := range names {
+		if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func connect() (*plex.Plex, error) {
+	if u := viper.GetString("url"); u != "" {
+		p, err := newPlex()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.UseURL(u); err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	}
+
+	return connectTo(viper.GetString("server"))
+}
+
+func newPlex() (*plex.Plex, error) {
+	p, err := plex.New(viper.GetString("token"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if viper.IsSet("concurrency") {
+		if err := p.SetConcurrency(viper.GetInt("concurrency")); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func connectTo(serverName string) (*plex.Plex, error) {
+	p, err := newPlex()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(serverName, "http://") || strings.HasPrefix(serverName, "https://") {
+		if err := p.UseURL(serverName); err != nil {
+			return nil, err
+		}
+
+		return p, nil
+	}
+
+	p.SetConnectionPreference(viper.GetString("connection"), viper.GetBool("prefer-local"))
+
+	var server *plex.Server
+
+	if serverName == "" {
+		server, err = p.PromptForServer()
+	} else {
+		server, err = p.GetServerByName(serverName)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.UseServer(server); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+
+	signal.Notify(c, os.Interrupt)
+
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
+
+		signal.Stop(c)
+	}()
+
+	return ctx, cancel
+}
+
+func libraryKey(p *plex.Plex) (string, error) {
+	key := viper.GetString("library")
+
+	if key != "" {
+		return key, nil
+	}
+
+	return p.PromptForLibraryKey()
+}
+
+func libraryKeys(p *plex.Plex) ([]string, error) {
+	if viper.GetBool("all-libraries") {
+		return p.LibraryKeys()
+	}
+
+	libraries := librarySlice()
+
+	if len(libraries) == 0 {
+		key, err := p.PromptForLibraryKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{key}, nil
+	}
+
+	keys := make([]string, len(libraries))
+
+	for k, l := range libraries {
+		key, err := p.ResolveLibraryKey(l)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys[k] = key
+	}
+
+	return keys, nil
+}
+
+// librarySlice reads the libraries to probe. A configuration file or
+// environment variable may hold them as a single string, which is split on
+// commas only, since library titles such as "TV Shows" contain spaces.
+func librarySlice() []string {
+	value, ok := viper.Get("library").(string)
+
+	if !ok {
+		return viper.GetStringSlice("library")
+	}
+
+	libraries := make([]string, 0)
+
+	for _, l := range strings.Split(value, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			libraries = append(libraries, l)
+		}
+	}
+
+	return libraries
+}
+
+func probeLibraries(ctx context.Context, p *plex.Plex) (*plex.Probe, error) {
+	var filter *plex.Filter
+
+	if expression := viper.GetString("filter"); expression != "" {
+		f, err := plex.ParseFilter(expression)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filter = f
+	}
+
+	keys, err := libraryKeys(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	probes := make([]*plex.Probe, len(keys))
+	progress := newProgressPrinter()
+
+	progress.attach(p)
+
+	defer progress.done()
+
+	for k, key := range keys {
+		probes[k], err = p.Probe(ctx, key)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	probe := plex.CombineProbes(probes)
+
+	if filter != nil {
+		probe = probe.Filter(filter)
+	}
+
+	return probe, nil
+}
+
+// saveConfig writes the given settings to the config file. Only the keys
+// already present in the file are kept alongside them, so that defaults and
+// flags of the current command don't end up in the file.
+func saveConfig(values map[string]interface{}) error {
+	path := viper.ConfigFileUsed()
+	v := viper.New()
+
+	v.SetConfigFile(path)
+
+	if _, err := os.Stat(path); err == nil {
+		if err := v.ReadInConfig(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	for key, value := range values {
+		v.Set(key, value)
+	}
+
+	if err := v.WriteConfig(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "Configuration saved to \"%s\"\n", path)
+
+	return nil
+}
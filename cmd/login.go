@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Sign in to Plex",
+	Long: `This tool signs in to Plex using a link code. Open https://plex.tv/link in a
+browser where you're signed in to Plex, enter the code shown and the resulting
+access token is saved to the config file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tv, err := plex.NewPlexTV("")
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := interruptContext()
+
+		defer cancel()
+
+		token, err := plex.Login(ctx, tv, 2*time.Second, func(code string) {
+			_, _ = fmt.Fprintf(os.Stderr, "Open https://plex.tv/link and enter the code: %s\n", code)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return saveConfig(map[string]interface{}{"token": token})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(loginCmd)
+}
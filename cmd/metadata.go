@@ -1,8 +1,11 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
 	"github.com/spf13/cobra"
+	"os"
+	"strings"
 )
 
 var metadataCmd = &cobra.Command{
@@ -10,13 +13,136 @@ var metadataCmd = &cobra.Command{
 	Short: "Update Plex metadata",
 	Long: `This tool updates your media's Plex metadata with information fetched from
 various online sources. Much like the built-in agents in Plex itself, but with a
-lot more flexibility and control!`,
+lot more flexibility and control!
+
+The nfo provider reads .nfo files next to your media using the file paths
+reported by the server, so it has to run on a machine where those paths are
+available locally, such as the server itself.`,
 	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindFlags(cmd, "concurrency", "connection", "library", "prefer-local", "server", "token", "url")
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("not yet implemented")
+		var provider plex.MetadataProvider
+
+		switch cmd.Flag("provider").Value.String() {
+		case "nfo":
+			provider = plex.NewNfoProvider()
+		default:
+			return fmt.Errorf("\"%s\" is not a supported metadata provider", cmd.Flag("provider").Value.String())
+		}
+
+		fields, err := cmd.Flags().GetStringSlice("fields")
+
+		if err != nil {
+			return err
+		}
+
+		for _, f := range fields {
+			if !isMetadataField(f) {
+				return fmt.Errorf("\"%s\" is not a supported field, expected one of %s", f, strings.Join(plex.MetadataFields, ", "))
+			}
+		}
+
+		p, err := connect()
+
+		if err != nil {
+			return err
+		}
+
+		key, err := libraryKey(p)
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := interruptContext()
+
+		defer cancel()
+
+		items, err := p.MetadataItems(ctx, key)
+
+		if err != nil {
+			return err
+		}
+
+		updates := make([]*plex.MetadataUpdate, 0)
+
+		for _, item := range items {
+			metadata, err := provider.Lookup(item.Query())
+
+			if err != nil {
+				return fmt.Errorf("%s lookup of \"%s\" failed: %s", provider.Name(), item.Title, err)
+			}
+
+			if metadata == nil {
+				continue
+			}
+
+			if err := p.LoadMetadata(ctx, item); err != nil {
+				return fmt.Errorf("unable to load metadata of \"%s\": %s", item.Title, err)
+			}
+
+			if u := plex.NewMetadataUpdate(item, metadata, fields); len(u.Changes) > 0 {
+				updates = append(updates, u)
+			}
+		}
+
+		plex.MetadataUpdatesAscii(os.Stdout, updates)
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+
+		if err != nil || dryRun || len(updates) == 0 {
+			return err
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+
+		if err != nil {
+			return err
+		}
+
+		if !yes {
+			confirmed, err := plex.PromptForConfirmation(fmt.Sprintf("Update %d items", len(updates)))
+
+			if err != nil || !confirmed {
+				return err
+			}
+		}
+
+		for _, u := range updates {
+			if err := p.ApplyMetadata(ctx, key, u); err != nil {
+				return fmt.Errorf("unable to update \"%s\": %s", u.Item.Title, err)
+			}
+
+			_, _ = fmt.Fprintf(os.Stderr, "Updated %s\n", u.Item.Title)
+		}
+
+		return nil
 	},
 }
 
+func isMetadataField(field string) bool {
+	for _, f := range plex.MetadataFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
 func init() {
+	metadataCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	metadataCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	metadataCmd.Flags().Bool("dry-run", false, "preview changes without applying them")
+	metadataCmd.Flags().StringSlice("fields", plex.MetadataFields, "fields to update")
+	metadataCmd.Flags().String("library", "", "Plex library key")
+	metadataCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	metadataCmd.Flags().String("provider", "nfo", "metadata provider")
+	metadataCmd.Flags().String("server", "", "Plex server name")
+	metadataCmd.Flags().String("token", "", "Plex access token")
+	metadataCmd.Flags().String("url", "", "Plex Media Server URL, bypassing server discovery")
+	metadataCmd.Flags().BoolP("yes", "y", false, "apply changes without asking for confirmation")
 	rootCmd.AddCommand(metadataCmd)
 }
@@ -2,80 +2,88 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/jyggen/plex-tools/plex"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"os"
 )
 
 var probeCmd = &cobra.Command{
-	Use:  "probe",
+	Use:   "probe",
+	Short: "List the media of one or more libraries",
+	Long: `This tool lists every file in one or more libraries along with its quality,
+codecs, size and bit rate, as an ascii table, an html report, json or csv.
+
+The json and csv formats emit raw values: sizes in bytes, durations in
+nanoseconds and bit rates as numbers. Unless --columns is given, they contain
+these keys, in this order:
+
+  title, year, duration, rating, size, quality, bitrate, video_codec,
+  frame_rate, audio_codec, audio_channels, guid, rating_key, media_id, files,
+  audio_languages, subtitle_languages, dynamic_range, bit_depth, type, artist,
+  album, track, sample_rate, width, height, library, show, season, episode,
+  episodes, qualities, mixed_quality, dimensions
+
+These keys are a stable contract. New keys may be appended, but existing keys
+are never renamed, reordered or removed. In csv, lists are joined with ";".
+
+--filter expressions use the same keys, except mixed_quality, and combine
+comparisons with and, or, not and parentheses. A key with several values, such
+as audio_languages, matches "=" and "~" if any of its values does and "!=" if
+none of them is equal, e.g. 'audio_languages != eng'.`,
 	Args: cobra.NoArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
-			return err
-		}
-
-		if err := viper.BindPFlag("library", cmd.Flags().Lookup("library")); err != nil {
-			return err
-		}
-
-		if err := viper.BindPFlag("server", cmd.Flags().Lookup("server")); err != nil {
-			return err
-		}
-
-		if err := viper.BindPFlag("token", cmd.Flags().Lookup("token")); err != nil {
-			return err
-		}
-
-		return nil
+		return bindFlags(cmd, "all-libraries", "cdn", "columns", "concurrency", "connection", "filter", "format", "level", "library", "prefer-local", "quiet", "server", "sort", "streams", "token", "url")
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		p, err := plex.New(viper.GetString("token"))
+		p, err := connect()
 
 		if err != nil {
 			return err
 		}
 
-		serverName := viper.GetString("server")
+		p.SetLoadStreams(viper.GetBool("streams"))
 
-		var server *plex.Server
+		ctx, cancel := interruptContext()
 
-		if serverName == "" {
-			server, err = p.PromptForServer()
-		} else {
-			server, err = p.GetServerByName(serverName)
-		}
+		defer cancel()
+
+		probe, err := probeLibraries(ctx, p)
 
 		if err != nil {
 			return err
 		}
 
-		p.UseServer(server)
+		probe, err = probe.Aggregate(viper.GetString("level"))
 
-		libraryKey := viper.GetString("library")
+		if err != nil {
+			return err
+		}
 
-		if libraryKey == "" {
-			libraryKey, err = p.PromptForLibraryKey()
+		probe.SetCDN(viper.GetBool("cdn"))
 
-			if err != nil {
-				return err
-			}
+		if err := probe.SetColumns(viper.GetStringSlice("columns")); err != nil {
+			return err
 		}
 
-		probe, err := p.Probe(libraryKey)
-
-		if err != nil {
+		if err := probe.SortBy(viper.GetStringSlice("sort")); err != nil {
 			return err
 		}
 
 		switch viper.GetString("format") {
 		case "ascii":
 			probe.Ascii(os.Stdout)
+		case "csv":
+			if err := probe.Csv(os.Stdout); err != nil {
+				return err
+			}
 		case "html":
 			if err := probe.Html(os.Stdout); err != nil {
 				return err
 			}
+		case "json":
+			if err := probe.Json(os.Stdout); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("\"%s\" is not a supported output format", viper.GetString("format"))
 		}
@@ -85,9 +93,21 @@ var probeCmd = &cobra.Command{
 }
 
 func init() {
-	probeCmd.Flags().String("format", "ascii", "output format")
-	probeCmd.Flags().String("library", "", "Plex library key")
+	probeCmd.Flags().Bool("all-libraries", false, "probe every library on the server")
+	probeCmd.Flags().Bool("cdn", false, "link Bootstrap, jQuery, Popper and Tablesort from their CDNs instead of inlining stand-ins for them")
+	probeCmd.Flags().StringSlice("columns", []string{}, "columns to show, in order (default depends on the library type)")
+	probeCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	probeCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	probeCmd.Flags().String("filter", "", "only include media matching an expression, e.g. 'quality < 1080p and size > 20GB'")
+	probeCmd.Flags().String("format", "ascii", "output format (ascii, csv, html or json)")
+	probeCmd.Flags().String("level", "episode", "aggregate TV libraries per episode, season or show")
+	probeCmd.Flags().StringSlice("library", []string{}, "Plex library key or title, may be repeated")
+	probeCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	probeCmd.Flags().Bool("quiet", false, "disable progress reporting on stderr")
 	probeCmd.Flags().String("server", "", "Plex server name")
+	probeCmd.Flags().StringSlice("sort", []string{}, "sort keys, e.g. size:desc,title (default server order)")
+	probeCmd.Flags().Bool("streams", false, "load audio, subtitle and video stream details, including sample rate and bit depth for music (slower)")
 	probeCmd.Flags().String("token", "", "Plex access token")
+	probeCmd.Flags().String("url", "", "Plex Media Server URL, bypassing server discovery")
 	rootCmd.AddCommand(probeCmd)
 }
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const progressInterval = 100 * time.Millisecond
+
+// progressPrinter redraws the latest progress on every tick rather than on
+// every update, so that the elapsed time keeps running while the server is
+// slow to answer.
+type progressPrinter struct {
+	mutex    sync.Mutex
+	printed  bool
+	progress *plex.Progress
+	started  time.Time
+	stop     chan struct{}
+	stopped  chan struct{}
+	w        io.Writer
+}
+
+func newProgressPrinter() *progressPrinter {
+	if viper.GetBool("quiet") {
+		return nil
+	}
+
+	if !isatty.IsTerminal(os.Stderr.Fd()) && !isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+		return nil
+	}
+
+	return &progressPrinter{w: os.Stderr}
+}
+
+func (pp *progressPrinter) attach(p *plex.Plex) {
+	if pp == nil {
+		return
+	}
+
+	p.SetProgress(pp.update)
+
+	if pp.stop != nil {
+		return
+	}
+
+	pp.stop = make(chan struct{})
+	pp.stopped = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+
+		defer ticker.Stop()
+		defer close(pp.stopped)
+
+		for {
+			select {
+			case <-pp.stop:
+				return
+			case <-ticker.C:
+				pp.draw()
+			}
+		}
+	}()
+}
+
+func (pp *progressPrinter) update(progress plex.Progress) {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	pp.progress = &progress
+	pp.started = time.Now().Add(-progress.Elapsed)
+}
+
+func (pp *progressPrinter) draw() {
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.progress == nil {
+		return
+	}
+
+	pp.printed = true
+
+	_, _ = fmt.Fprintf(
+		pp.w,
+		"\r\033[KDiscovered %d items, fetched %d shows, seasons and albums (%s)",
+		pp.progress.Items,
+		pp.progress.Containers,
+		time.Since(pp.started).Round(time.Second),
+	)
+}
+
+func (pp *progressPrinter) done() {
+	if pp == nil {
+		return
+	}
+
+	if pp.stop != nil {
+		close(pp.stop)
+		<-pp.stopped
+
+		pp.stop = nil
+	}
+
+	pp.mutex.Lock()
+	defer pp.mutex.Unlock()
+
+	if pp.printed {
+		_, _ = fmt.Fprint(pp.w, "\r\033[K")
+		pp.printed = false
+	}
+
+	pp.progress = nil
+}
@@ -44,6 +44,7 @@ var rootCmd = &cobra.Command{
 }
 
 func init() {
+	viper.SetDefault("prefer-local", true)
 	rootCmd.PersistentFlags().StringP("config", "c", "", fmt.Sprintf("config file (default \"$HOME/.%s.yaml\")", appName))
 }
 
@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"github.com/jyggen/plex-tools/plex"
+	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"os"
@@ -12,73 +12,77 @@ var statisticsCmd = &cobra.Command{
 	Short: "Display statistics about a library",
 	Args:  cobra.NoArgs,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
-			return err
-		}
+		return bindFlags(cmd, "all-libraries", "cdn", "concurrency", "connection", "filter", "format", "group-by", "histograms", "library", "percentiles", "prefer-local", "quiet", "server", "token", "url")
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := connect()
 
-		if err := viper.BindPFlag("library", cmd.Flags().Lookup("library")); err != nil {
+		if err != nil {
 			return err
 		}
 
-		if err := viper.BindPFlag("server", cmd.Flags().Lookup("server")); err != nil {
-			return err
-		}
+		ctx, cancel := interruptContext()
 
-		if err := viper.BindPFlag("token", cmd.Flags().Lookup("token")); err != nil {
-			return err
-		}
+		defer cancel()
 
-		return nil
-	},
-	RunE: func(cmd *cobra.Command, args []string) error {
-		p, err := plex.New(viper.GetString("token"))
+		probe, err := probeLibraries(ctx, p)
 
 		if err != nil {
 			return err
 		}
 
-		serverName := viper.GetString("server")
+		probe.SetCDN(viper.GetBool("cdn"))
 
-		var server *plex.Server
+		statistics := probe.Statistics()
 
-		if serverName == "" {
-			server, err = p.PromptForServer()
-		} else {
-			server, err = p.GetServerByName(serverName)
+		if err := statistics.SetGroupBy(viper.GetStringSlice("group-by")); err != nil {
+			return err
 		}
 
-		if err != nil {
+		if err := statistics.SetPercentiles(viper.GetStringSlice("percentiles")); err != nil {
 			return err
 		}
 
-		p.UseServer(server)
-
-		libraryKey := viper.GetString("library")
+		statistics.SetHistograms(viper.GetBool("histograms"))
 
-		if libraryKey == "" {
-			libraryKey, err = p.PromptForLibraryKey()
-
-			if err != nil {
+		switch viper.GetString("format") {
+		case "ascii":
+			statistics.Ascii(os.Stdout)
+		case "csv":
+			if err := statistics.Csv(os.Stdout); err != nil {
 				return err
 			}
+		case "html":
+			if err := statistics.Html(os.Stdout); err != nil {
+				return err
+			}
+		case "json":
+			if err := statistics.Json(os.Stdout); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("\"%s\" is not a supported output format", viper.GetString("format"))
 		}
 
-		probe, err := p.Probe(libraryKey)
-
-		if err != nil {
-			return err
-		}
-
-		probe.Statistics().Ascii(os.Stdout)
-
 		return nil
 	},
 }
 
 func init() {
-	statisticsCmd.Flags().String("format", "ascii", "output format")
-	statisticsCmd.Flags().String("library", "", "Plex library key")
+	statisticsCmd.Flags().Bool("all-libraries", false, "probe every library on the server")
+	statisticsCmd.Flags().Bool("cdn", false, "link Bootstrap, jQuery, Popper and Tablesort from their CDNs instead of inlining stand-ins for them")
+	statisticsCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	statisticsCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	statisticsCmd.Flags().String("filter", "", "only include media matching an expression, e.g. 'quality < 1080p and size > 20GB'")
+	statisticsCmd.Flags().String("format", "ascii", "output format (ascii, csv, html or json)")
+	statisticsCmd.Flags().StringSlice("group-by", []string{}, "compute metrics per audio_codec, decade, quality, show, video_codec or year")
+	statisticsCmd.Flags().Bool("histograms", false, "include size, bitrate, duration and decade histograms")
+	statisticsCmd.Flags().StringSlice("library", []string{}, "Plex library key or title, may be repeated")
+	statisticsCmd.Flags().StringSlice("percentiles", []string{"p10", "p25", "p75", "p90", "p99"}, "percentiles to report alongside the median")
+	statisticsCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	statisticsCmd.Flags().Bool("quiet", false, "disable progress reporting on stderr")
 	statisticsCmd.Flags().String("server", "", "Plex server name")
 	statisticsCmd.Flags().String("token", "", "Plex access token")
+	statisticsCmd.Flags().String("url", "", "Plex Media Server URL, bypassing server discovery")
 	rootCmd.AddCommand(statisticsCmd)
 }
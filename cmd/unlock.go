@@ -1,8 +1,10 @@
 package cmd
 
 import (
-	"errors"
+	"fmt"
+	"github.com/jyggen/plex-tools/plex"
 	"github.com/spf13/cobra"
+	"os"
 )
 
 var unlockCmd = &cobra.Command{
@@ -11,11 +13,71 @@ var unlockCmd = &cobra.Command{
 	Long: `This tool unlocks all metadata fields in Plex so Plex and its agents are able to
 modify them.`,
 	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return bindFlags(cmd, "concurrency", "connection", "library", "prefer-local", "server", "token", "url")
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("not yet implemented")
+		p, err := connect()
+
+		if err != nil {
+			return err
+		}
+
+		key, err := libraryKey(p)
+
+		if err != nil {
+			return err
+		}
+
+		fields, err := cmd.Flags().GetStringSlice("fields")
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := interruptContext()
+
+		defer cancel()
+
+		items, err := p.LockedItems(ctx, key, fields)
+
+		if err != nil {
+			return err
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+
+		if err != nil {
+			return err
+		}
+
+		if dryRun || len(items) == 0 {
+			plex.LockedItemsAscii(os.Stdout, items)
+
+			return nil
+		}
+
+		for _, item := range items {
+			if err := p.Unlock(ctx, key, item); err != nil {
+				return fmt.Errorf("unable to unlock \"%s\": %s", item.Title, err)
+			}
+
+			_, _ = fmt.Fprintf(os.Stderr, "Unlocked %s\n", item.Title)
+		}
+
+		return nil
 	},
 }
 
 func init() {
+	unlockCmd.Flags().Int("concurrency", 8, "maximum number of concurrent requests")
+	unlockCmd.Flags().String("connection", "", "connection to use: a URL, \"local\", \"remote\" or \"relay\"")
+	unlockCmd.Flags().Bool("dry-run", false, "list locked fields without unlocking them")
+	unlockCmd.Flags().StringSlice("fields", []string{}, "fields to unlock, e.g. title,summary,poster (default all)")
+	unlockCmd.Flags().String("library", "", "Plex library key")
+	unlockCmd.Flags().Bool("prefer-local", true, "prefer local connections over remote ones")
+	unlockCmd.Flags().String("server", "", "Plex server name")
+	unlockCmd.Flags().String("token", "", "Plex access token")
+	unlockCmd.Flags().String("url", "", "Plex Media Server URL, bypassing server discovery")
 	rootCmd.AddCommand(unlockCmd)
 }
@@ -0,0 +1,363 @@
+package extract
+
+import (
+	"fmt"
+	"github.com/nwaples/rardecode"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Extractor struct {
+	deleteArchives bool
+	log            io.Writer
+	temp           string
+}
+
+var partVolume = regexp.MustCompile(`(?i)\.part(\d+)\.rar$`)
+
+// New returns an extractor that stages files in temp before moving them into
+// place. When temp is empty, files are staged in a hidden directory next to
+// each archive, which keeps the final move a rename on the same filesystem.
+func New(temp string, deleteArchives bool, log io.Writer) (*Extractor, error) {
+	if temp == "" {
+		return &Extractor{
+			deleteArchives: deleteArchives,
+			log:            log,
+		}, nil
+	}
+
+	info, err := os.Stat(temp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("\"%s\" is not a directory", temp)
+	}
+
+	return &Extractor{
+		deleteArchives: deleteArchives,
+		log:            log,
+		temp:           temp,
+	}, nil
+}
+
+func IsFirstVolume(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+
+	if filepath.Ext(name) != ".rar" {
+		return false
+	}
+
+	if m := partVolume.FindStringSubmatch(name); m != nil {
+		n, err := strconv.Atoi(m[1])
+
+		return err == nil && n == 1
+	}
+
+	return true
+}
+
+func (e *Extractor) Walk(input string) error {
+	archives := make([]string, 0)
+
+	err := filepath.Walk(input, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() && IsFirstVolume(path) {
+			archives = append(archives, path)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, archive := range archives {
+		if err := e.Extract(archive); err != nil {
+			return fmt.Errorf("unable to extract \"%s\": %s", archive, err)
+		}
+	}
+
+	return nil
+}
+
+func (e *Extractor) Extract(archive string) error {
+	dir := filepath.Dir(archive)
+	extracted, err := isExtracted(archive, dir)
+
+	if err != nil {
+		return err
+	}
+
+	if extracted {
+		_, _ = fmt.Fprintf(e.log, "Skipping %s (already extracted)\n", archive)
+
+		return markExtracted(archive)
+	}
+
+	_, _ = fmt.Fprintf(e.log, "Extracting %s\n", archive)
+
+	temp := e.temp
+
+	if temp == "" {
+		temp = dir
+	}
+
+	staging, err := ioutil.TempDir(temp, ".extract-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(staging)
+
+	files, volumes, err := unpack(archive, staging)
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		if err := move(filepath.Join(staging, name), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	if !e.deleteArchives {
+		return markExtracted(archive)
+	}
+
+	for _, volume := range volumes {
+		if err := os.Remove(volume); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markerPath is the hidden file recording that an archive has been extracted.
+func markerPath(archive string) string {
+	return filepath.Join(filepath.Dir(archive), fmt.Sprintf(".%s.extracted", filepath.Base(archive)))
+}
+
+func markExtracted(archive string) error {
+	return ioutil.WriteFile(markerPath(archive), nil, 0644)
+}
+
+// isExtracted trusts a marker that is newer than the archive. Without one, it
+// compares the archive's entries with the files next to it, which reads every
+// volume in full since the entries can only be listed by skipping their data.
+func isExtracted(archive string, dir string) (bool, error) {
+	marker, err := os.Stat(markerPath(archive))
+
+	if err == nil {
+		info, err := os.Stat(archive)
+
+		if err != nil {
+			return false, err
+		}
+
+		if !info.ModTime().After(marker.ModTime()) {
+			return true, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	r, err := rardecode.OpenReader(archive, "")
+
+	if err != nil {
+		return false, err
+	}
+
+	defer r.Close()
+
+	found := false
+
+	for {
+		h, err := r.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if h.IsDir {
+			continue
+		}
+
+		path, err := entryPath(dir, h.Name)
+
+		if err != nil {
+			return false, err
+		}
+
+		info, err := os.Stat(path)
+
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if !h.UnKnownSize && info.Size() != h.UnPackedSize {
+			return false, nil
+		}
+
+		found = true
+	}
+
+	return found, nil
+}
+
+func unpack(archive string, staging string) ([]string, []string, error) {
+	r, err := rardecode.OpenReader(archive, "")
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer r.Close()
+
+	files := make([]string, 0)
+
+	for {
+		h, err := r.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		path, err := entryPath(staging, h.Name)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if h.IsDir {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return nil, nil, err
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, nil, err
+		}
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, h.Mode().Perm()|0600)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if _, err := io.Copy(f, r); err != nil {
+			_ = f.Close()
+
+			return nil, nil, err
+		}
+
+		if err := f.Close(); err != nil {
+			return nil, nil, err
+		}
+
+		if !h.ModificationTime.IsZero() {
+			_ = os.Chtimes(path, h.ModificationTime, h.ModificationTime)
+		}
+
+		files = append(files, filepath.FromSlash(h.Name))
+	}
+
+	volumes := make([]string, len(r.Volumes()))
+	copy(volumes, r.Volumes())
+
+	return files, volumes, nil
+}
+
+func entryPath(dir string, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+
+	if path != dir && !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal file path \"%s\" in archive", name)
+	}
+
+	return path, nil
+}
+
+func move(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	// The staging directory lives on another filesystem, so copy the file next
+	// to its destination under a hidden name and rename it into place from there.
+	tmp := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%s.partial", filepath.Base(dst)))
+
+	if err := copyFile(src, tmp); err != nil {
+		_ = os.Remove(tmp)
+
+		return err
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	info, err := in.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,288 @@
+package extract
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The archives in testdata are stored (uncompressed) RAR 4 archives holding a
+// single small text file, split over several volumes where named so.
+
+func copyTestdata(t *testing.T, name string) (string, func()) {
+	dir, err := ioutil.TempDir("", "extract")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join("testdata", name, "*"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, filepath.Base(f)), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func newExtractor(t *testing.T, deleteArchives bool, log *bytes.Buffer) *Extractor {
+	e, err := New("", deleteArchives, log)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+func assertFile(t *testing.T, path string, expected string) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != expected {
+		t.Errorf("expected \"%s\" to contain %q, got %q", path, expected, string(b))
+	}
+}
+
+func TestIsFirstVolume(t *testing.T) {
+	tests := map[string]bool{
+		"movie.rar":          true,
+		"Movie.RAR":          true,
+		"show.part1.rar":     true,
+		"show.part01.rar":    true,
+		"show.part001.rar":   true,
+		"show.part2.rar":     false,
+		"show.part10.rar":    false,
+		"film.r00":           false,
+		"film.r01":           false,
+		"movie.mkv":          false,
+		"movie.part1.rar.gz": false,
+	}
+
+	for name, expected := range tests {
+		if IsFirstVolume(name) != expected {
+			t.Errorf("expected IsFirstVolume(\"%s\") to be %t", name, expected)
+		}
+	}
+}
+
+func TestExtractSingleVolume(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "single")
+
+	defer cleanup()
+
+	if err := newExtractor(t, false, &bytes.Buffer{}).Walk(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, filepath.Join(dir, "movie.mkv"), "a single volume archive\n")
+
+	if _, err := os.Stat(filepath.Join(dir, "movie.rar")); err != nil {
+		t.Errorf("expected the archive to be kept: %s", err)
+	}
+}
+
+func TestExtractPartVolumes(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "parts")
+
+	defer cleanup()
+
+	var log bytes.Buffer
+
+	if err := newExtractor(t, false, &log).Walk(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, filepath.Join(dir, "episode.mkv"), "a multi-volume archive using .partNN.rar names\n")
+
+	if n := strings.Count(log.String(), "Extracting"); n != 1 {
+		t.Errorf("expected only the first volume to be extracted, got %d extractions:\n%s", n, log.String())
+	}
+}
+
+func TestExtractOldStyleVolumes(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "old")
+
+	defer cleanup()
+
+	var log bytes.Buffer
+
+	if err := newExtractor(t, false, &log).Walk(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, filepath.Join(dir, "film.mkv"), "a multi-volume archive using .rNN names\n")
+
+	if n := strings.Count(log.String(), "Extracting"); n != 1 {
+		t.Errorf("expected only the first volume to be extracted, got %d extractions:\n%s", n, log.String())
+	}
+}
+
+func TestExtractSkipsExtracted(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "single")
+
+	defer cleanup()
+
+	var log bytes.Buffer
+
+	e := newExtractor(t, false, &log)
+
+	if err := e.Extract(filepath.Join(dir, "movie.rar")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Extract(filepath.Join(dir, "movie.rar")); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(log.String(), "Skipping") {
+		t.Errorf("expected the second extraction to be skipped, got:\n%s", log.String())
+	}
+}
+
+func TestExtractTrustsMarker(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "single")
+
+	defer cleanup()
+
+	archive := filepath.Join(dir, "movie.rar")
+	e := newExtractor(t, false, &bytes.Buffer{})
+
+	if err := e.Extract(archive); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".movie.rar.extracted")); err != nil {
+		t.Fatalf("expected a marker: %s", err)
+	}
+
+	// A marked archive is skipped without being read, so corrupting it must
+	// not matter as long as it is older than the marker.
+	if err := ioutil.WriteFile(archive, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+
+	if err := os.Chtimes(archive, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Extract(archive); err != nil {
+		t.Errorf("expected the marked archive to be skipped, got %s", err)
+	}
+
+	staging, err := filepath.Glob(filepath.Join(dir, ".extract-*"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(staging) != 0 {
+		t.Errorf("expected the staging directory to be removed, found %s", strings.Join(staging, ", "))
+	}
+}
+
+func TestExtractReplacesIncompleteFile(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "single")
+
+	defer cleanup()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "movie.mkv"), []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newExtractor(t, false, &bytes.Buffer{}).Extract(filepath.Join(dir, "movie.rar")); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, filepath.Join(dir, "movie.mkv"), "a single volume archive\n")
+}
+
+func TestExtractDeletesArchives(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "parts")
+
+	defer cleanup()
+
+	if err := newExtractor(t, true, &bytes.Buffer{}).Walk(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	assertFile(t, filepath.Join(dir, "episode.mkv"), "a multi-volume archive using .partNN.rar names\n")
+
+	volumes, err := filepath.Glob(filepath.Join(dir, "*.rar"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(volumes) != 0 {
+		t.Errorf("expected every volume to be deleted, found %s", strings.Join(volumes, ", "))
+	}
+}
+
+func TestExtractRejectsPathTraversal(t *testing.T) {
+	dir, cleanup := copyTestdata(t, "traversal")
+
+	defer cleanup()
+
+	err := newExtractor(t, true, &bytes.Buffer{}).Extract(filepath.Join(dir, "evil.rar"))
+
+	if err == nil || !strings.Contains(err.Error(), "illegal file path") {
+		t.Errorf("expected an illegal file path error, got %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "evil.txt")); !os.IsNotExist(err) {
+		t.Error("expected nothing to be written outside the archive directory")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "evil.rar")); err != nil {
+		t.Errorf("expected the archive to be kept: %s", err)
+	}
+}
+
+func TestEntryPath(t *testing.T) {
+	dir := filepath.FromSlash("/data/downloads")
+
+	valid := map[string]string{
+		"movie.mkv":           filepath.FromSlash("/data/downloads/movie.mkv"),
+		"subs/movie.srt":      filepath.FromSlash("/data/downloads/subs/movie.srt"),
+		"subs/../movie.nfo":   filepath.FromSlash("/data/downloads/movie.nfo"),
+		"./sample/sample.mkv": filepath.FromSlash("/data/downloads/sample/sample.mkv"),
+	}
+
+	for name, expected := range valid {
+		path, err := entryPath(dir, name)
+
+		if err != nil || path != expected {
+			t.Errorf("expected entryPath(\"%s\") to be \"%s\", got \"%s\" (%v)", name, expected, path, err)
+		}
+	}
+
+	for _, name := range []string{"../movie.mkv", "subs/../../movie.mkv", "../downloads-other/movie.mkv"} {
+		if _, err := entryPath(dir, name); err == nil {
+			t.Errorf("expected entryPath(\"%s\") to be rejected", name)
+		}
+	}
+}
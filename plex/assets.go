@@ -0,0 +1,142 @@
+package plex
+
+import (
+	"html/template"
+)
+
+// cdnStylesheet and cdnScripts link Bootstrap, jQuery, Popper and Tablesort
+// from their CDNs, for reports that are only viewed online.
+const cdnStylesheet = `<link rel="stylesheet" href="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/css/bootstrap.min.css" integrity="sha384-ggOyR0iXCbMQv3Xipma34MD+dH/1fQ784/j6cY/iJTQUOhcWr7x9JvoRxT2MZw1T" crossorigin="anonymous">`
+
+const cdnScripts = `<script src="https://code.jquery.com/jquery-3.3.1.slim.min.js" integrity="sha384-q8i/X+965DzO0rT7abK41JStQIAqVgRVzpbzo5smXKp4YfRvH+8abtTE1Pi6jizo" crossorigin="anonymous"></script>
+		<script src="https://cdnjs.cloudflare.com/ajax/libs/popper.js/1.14.7/umd/popper.min.js" integrity="sha384-UO2eT0CpHqdSJQ6hJty5KVphtPhzWj9WO1clHTMGa3JDZwrnQq4sF86dIHNDz0W1" crossorigin="anonymous"></script>
+		<script src="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/js/bootstrap.min.js" integrity="sha384-JjSmVgyd0p3pXB1rRibZUAYoIIy6OrQ6VrjIEaFf/nJGzIxFDsf4x0xIM+B07jRM" crossorigin="anonymous"></script>
+		<script src="https://cdnjs.cloudflare.com/ajax/libs/tablesort/5.1.0/tablesort.min.js" integrity="sha256-p3wukcf2d2jxbVnlqPDO9t4AAjnl42D2aIzrK4S0X6w=" crossorigin="anonymous"></script>
+		<script src="https://cdnjs.cloudflare.com/ajax/libs/tablesort/5.1.0/sorts/tablesort.number.min.js" integrity="sha256-ra1pWQ7MfuVIolZ/phcEXegs9m1ehXaCNI8cmc3gJEs=" crossorigin="anonymous"></script>
+		<script>
+		Array.prototype.forEach.call(document.querySelectorAll('table.sortable'), function (table) {
+			new Tablesort(table);
+		});
+		</script>`
+
+// reportStylesheet is a stand-in for the subset of Bootstrap classes used by
+// the HTML reports, so that they render without network access.
+const reportStylesheet = `*, *::before, *::after { box-sizing: border-box; }
+body { margin: 0; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Helvetica Neue", Arial, sans-serif; font-size: 1rem; line-height: 1.5; color: #212529; background-color: #fff; }
+h1, h2 { margin: 1rem .75rem .5rem; font-weight: 500; line-height: 1.2; }
+h1 { font-size: 1.75rem; }
+h2 { font-size: 1.5rem; }
+.form-control { display: block; width: calc(100% - 1.5rem); margin: .5rem .75rem; padding: .375rem .75rem; font-size: 1rem; color: #495057; background-color: #fff; border: 1px solid #ced4da; border-radius: .25rem; }
+.form-control:focus { border-color: #80bdff; outline: 0; box-shadow: 0 0 0 .2rem rgba(0, 123, 255, .25); }
+.table { width: 100%; margin-bottom: 1rem; color: #212529; border-collapse: collapse; }
+.table th, .table td { padding: .75rem; vertical-align: top; border-top: 1px solid #dee2e6; text-align: left; }
+.table thead th { vertical-align: bottom; border-bottom: 2px solid #dee2e6; }
+.table-sm th, .table-sm td { padding: .3rem; }
+.table-striped tbody tr:nth-of-type(odd) { background-color: rgba(0, 0, 0, .05); }
+.table .thead-dark th { color: #fff; background-color: #343a40; border-color: #454d55; }
+.w-50 { width: 50%; }`
+
+// reportExtraStylesheet styles the parts of the reports that Bootstrap has no
+// classes for.
+const reportExtraStylesheet = `.summary { display: flex; flex-wrap: wrap; margin: .5rem .375rem; }
+.summary div { flex: 1 1 10rem; margin: .375rem; padding: .5rem .75rem; border: 1px solid #dee2e6; border-radius: .25rem; }
+.summary dt { font-size: .875rem; font-weight: 400; color: #6c757d; }
+.summary dd { margin: 0; font-size: 1.25rem; }
+.bar { height: 1rem; background-color: #007bff; border-radius: .125rem; }
+.sortable th { cursor: pointer; user-select: none; }
+.sortable th[aria-sort="ascending"]::after { content: " \25B2"; }
+.sortable th[aria-sort="descending"]::after { content: " \25BC"; }`
+
+// reportSortScript is a stand-in for Tablesort. It makes tables with the
+// "sortable" class sortable by clicking their headers.
+const reportSortScript = `(function () {
+	function value(row, index) {
+		var cell = row.cells[index];
+
+		if (!cell) {
+			return '';
+		}
+
+		return cell.hasAttribute('data-sort') ? cell.getAttribute('data-sort') : cell.textContent;
+	}
+
+	function compare(a, b) {
+		var x = parseFloat(a), y = parseFloat(b);
+
+		if (!isNaN(x) && !isNaN(y) && String(x) === a.trim() && String(y) === b.trim()) {
+			return x - y;
+		}
+
+		return a.toLowerCase().localeCompare(b.toLowerCase());
+	}
+
+	Array.prototype.forEach.call(document.querySelectorAll('table.sortable'), function (table) {
+		var headers = table.tHead.rows[0].cells;
+
+		Array.prototype.forEach.call(headers, function (header, index) {
+			header.addEventListener('click', function () {
+				var descending = header.getAttribute('aria-sort') === 'ascending';
+				var body = table.tBodies[0];
+				var rows = Array.prototype.slice.call(body.rows);
+
+				Array.prototype.forEach.call(headers, function (h) {
+					h.removeAttribute('aria-sort');
+				});
+
+				header.setAttribute('aria-sort', descending ? 'descending' : 'ascending');
+
+				rows.sort(function (a, b) {
+					var c = compare(value(a, index), value(b, index));
+
+					return descending ? -c : c;
+				});
+
+				rows.forEach(function (row) {
+					body.appendChild(row);
+				});
+			});
+		});
+	});
+})();`
+
+// reportSearchScript filters the rows of the table named in an input's
+// data-table attribute as the user types.
+const reportSearchScript = `(function () {
+	Array.prototype.forEach.call(document.querySelectorAll('input[data-table]'), function (input) {
+		var table = document.getElementById(input.getAttribute('data-table'));
+
+		input.addEventListener('input', function () {
+			var terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+
+			Array.prototype.forEach.call(table.tBodies[0].rows, function (row) {
+				var text = row.textContent.toLowerCase();
+				var visible = terms.every(function (term) {
+					return text.indexOf(term) !== -1;
+				});
+
+				row.style.display = visible ? '' : 'none';
+			});
+		});
+	});
+})();`
+
+// htmlFuncs inlines the stand-in assets, or links the real ones from their
+// CDNs when cdn is set.
+func htmlFuncs(cdn bool) template.FuncMap {
+	return template.FuncMap{
+		"script": func() template.HTML {
+			if cdn {
+				return template.HTML(cdnScripts + "\n\t\t<script>" + reportSearchScript + "</script>")
+			}
+
+			return template.HTML("<script>" + reportSortScript + "\n" + reportSearchScript + "</script>")
+		},
+		"stylesheet": func() template.HTML {
+			if cdn {
+				return template.HTML(cdnStylesheet + "\n\t\t<style>" + reportExtraStylesheet + "</style>")
+			}
+
+			return template.HTML("<style>" + reportStylesheet + "\n" + reportExtraStylesheet + "</style>")
+		},
+	}
+}
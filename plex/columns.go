@@ -0,0 +1,479 @@
+package plex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type probeColumn struct {
+	display func(m *Media) []string
+	raw     func(m *Media) interface{}
+	sort    func(m *Media) interface{}
+	title   string
+}
+
+type probeCell struct {
+	Lines []string
+	Sort  string
+}
+
+var probeColumns = map[string]probeColumn{
+	"album":              textColumn("Album", func(m *Media) string { return m.Album }),
+	"artist":             textColumn("Artist", func(m *Media) string { return m.Artist }),
+	"audio_channels":     integerColumn("Channels", func(m *Media) int64 { return int64(m.AudioChannels) }, nil),
+	"audio_codec":        textColumn("Audio", func(m *Media) string { return m.AudioCodec }),
+	"audio_languages":    listColumn("Audio Languages", func(m *Media) []string { return m.AudioLanguages() }),
+	"bit_depth":          integerColumn("Bit Depth", func(m *Media) int64 { return int64(m.BitDepth()) }, nil),
+	"bitrate":            integerColumn("Bit Rate", func(m *Media) int64 { return int64(m.Bitrate) }, (*Media).HumanizeBitRate),
+	"dimensions":         dimensionsColumn(),
+	"duration":           integerColumn("Duration", func(m *Media) int64 { return m.DurationInNanoseconds() }, (*Media).HumanizeDuration),
+	"dynamic_range":      textColumn("Dynamic Range", (*Media).DynamicRange),
+	"episode":            integerColumn("Episode", func(m *Media) int64 { return int64(m.Episode) }, nil),
+	"episodes":           integerColumn("Episodes", func(m *Media) int64 { return int64(m.Episodes) }, nil),
+	"files":              filesColumn(),
+	"frame_rate":         textColumn("Frame Rate", func(m *Media) string { return m.FrameRate }),
+	"guid":               textColumn("GUID", func(m *Media) string { return m.GUID }),
+	"height":             integerColumn("Height", func(m *Media) int64 { return int64(m.Height) }, nil),
+	"library":            textColumn("Library", func(m *Media) string { return m.Library }),
+	"media_id":           integerColumn("Media ID", func(m *Media) int64 { return int64(m.ID) }, nil),
+	"mixed_quality":      mixedQualityColumn(),
+	"qualities":          listColumn("Qualities", func(m *Media) []string { return m.Qualities }),
+	"quality":            qualityColumn(),
+	"rating":             numberColumn("Rating", func(m *Media) float64 { return m.Rating }, nil),
+	"rating_key":         textColumn("Rating Key", func(m *Media) string { return m.RatingKey }),
+	"season":             integerColumn("Season", func(m *Media) int64 { return int64(m.Season) }, nil),
+	"sample_rate":        integerColumn("Sample Rate", func(m *Media) int64 { return int64(m.SamplingRate()) }, nil),
+	"show":               textColumn("Show", func(m *Media) string { return m.Show }),
+	"size":               integerColumn("Size", func(m *Media) int64 { return int64(m.Size) }, (*Media).HumanizeSize),
+	"subtitle_languages": listColumn("Subtitles", func(m *Media) []string { return m.SubtitleLanguages() }),
+	"title":              titleColumn(),
+	"track":              integerColumn("Track", func(m *Media) int64 { return int64(m.Track) }, nil),
+	"type":               textColumn("Type", func(m *Media) string { return m.Type }),
+	"video_codec":        textColumn("Video", func(m *Media) string { return m.VideoCodec }),
+	"width":              integerColumn("Width", func(m *Media) int64 { return int64(m.Width) }, nil),
+	"year":               integerColumn("Year", func(m *Media) int64 { return int64(m.Year) }, nil),
+}
+
+func textColumn(title string, value func(m *Media) string) probeColumn {
+	return probeColumn{
+		display: func(m *Media) []string { return []string{value(m)} },
+		raw:     func(m *Media) interface{} { return value(m) },
+		sort:    func(m *Media) interface{} { return strings.ToLower(value(m)) },
+		title:   title,
+	}
+}
+
+func numberColumn(title string, value func(m *Media) float64, format func(m *Media) string) probeColumn {
+	if format == nil {
+		format = func(m *Media) string { return formatFloat(value(m)) }
+	}
+
+	return probeColumn{
+		display: func(m *Media) []string { return []string{format(m)} },
+		raw:     func(m *Media) interface{} { return value(m) },
+		sort:    func(m *Media) interface{} { return value(m) },
+		title:   title,
+	}
+}
+
+func integerColumn(title string, value func(m *Media) int64, format func(m *Media) string) probeColumn {
+	c := numberColumn(title, func(m *Media) float64 { return float64(value(m)) }, format)
+
+	if format == nil {
+		c.display = func(m *Media) []string { return []string{strconv.FormatInt(value(m), 10)} }
+	}
+
+	c.raw = func(m *Media) interface{} { return value(m) }
+
+	return c
+}
+
+func listColumn(title string, value func(m *Media) []string) probeColumn {
+	return probeColumn{
+		display: func(m *Media) []string { return []string{joinLanguages(value(m))} },
+		raw:     func(m *Media) interface{} { return value(m) },
+		sort:    func(m *Media) interface{} { return strings.ToLower(joinLanguages(value(m))) },
+		title:   title,
+	}
+}
+
+func dimensionsColumn() probeColumn {
+	c := textColumn("Dimensions", (*Media).Dimensions)
+
+	c.sort = func(m *Media) interface{} { return float64(m.Width * m.Height) }
+
+	return c
+}
+
+func filesColumn() probeColumn {
+	return probeColumn{
+		display: (*Media).Files,
+		raw:     func(m *Media) interface{} { return m.Files() },
+		sort:    func(m *Media) interface{} { return strings.ToLower(m.File()) },
+		title:   "File",
+	}
+}
+
+func mixedQualityColumn() probeColumn {
+	return probeColumn{
+		display: func(m *Media) []string {
+			if m.MixedQuality() {
+				return []string{"yes"}
+			}
+
+			return []string{"no"}
+		},
+		raw: func(m *Media) interface{} { return m.MixedQuality() },
+		sort: func(m *Media) interface{} {
+			if m.MixedQuality() {
+				return float64(1)
+			}
+
+			return float64(0)
+		},
+		title: "Mixed Quality",
+	}
+}
+
+func qualityColumn() probeColumn {
+	c := textColumn("Quality", func(m *Media) string { return m.Quality })
+
+	c.sort = func(m *Media) interface{} { return qualityRank(m.Quality) }
+
+	return c
+}
+
+func titleColumn() probeColumn {
+	c := textColumn("Title", func(m *Media) string { return m.Title })
+
+	c.sort = func(m *Media) interface{} { return strings.ToLower(m.SortTitle()) }
+
+	return c
+}
+
+func (p *Probe) defaultColumns() []string {
+	var columns []string
+
+	switch {
+	case p.level == "season" || p.level == "show":
+		columns = []string{"title", "year", "episodes", "duration", "size", "quality", "mixed_quality", "qualities", "bitrate", "video_codec", "audio_codec"}
+	case p.kind == "music":
+		columns = []string{"artist", "album", "track", "title", "year", "duration", "size", "bitrate", "audio_codec", "audio_channels"}
+
+		if p.streams {
+			columns = append(columns, "sample_rate", "bit_depth")
+		}
+	case p.kind == "photo":
+		columns = []string{"title", "album", "year", "dimensions", "size"}
+	default:
+		columns = []string{"title", "year", "duration", "rating", "size", "quality", "bitrate", "video_codec", "frame_rate", "audio_codec", "audio_channels"}
+
+		if p.streams {
+			columns = append(columns, "audio_languages", "subtitle_languages", "dynamic_range", "bit_depth")
+		}
+	}
+
+	if p.level != "season" && p.level != "show" {
+		columns = append(columns, "files")
+	}
+
+	if p.Combined() {
+		columns = append([]string{"library"}, columns...)
+	}
+
+	return columns
+}
+
+func (p *Probe) Columns() []string {
+	if len(p.columns) > 0 {
+		return p.columns
+	}
+
+	return p.defaultColumns()
+}
+
+func (p *Probe) SetColumns(columns []string) error {
+	normalized := make([]string, 0, len(columns))
+
+	for _, c := range columns {
+		c = strings.ToLower(strings.TrimSpace(c))
+
+		if c == "" {
+			continue
+		}
+
+		if _, ok := probeColumns[c]; !ok {
+			return fmt.Errorf("\"%s\" is not a valid column, expected one of %s", c, strings.Join(ColumnNames(), ", "))
+		}
+
+		normalized = append(normalized, c)
+	}
+
+	p.columns = normalized
+
+	return nil
+}
+
+func ColumnNames() []string {
+	names := make([]string, 0, len(probeColumns))
+
+	for k := range probeColumns {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (p *Probe) SortBy(keys []string) error {
+	type sortKey struct {
+		column     probeColumn
+		descending bool
+	}
+
+	sortKeys := make([]sortKey, 0, len(keys))
+
+	for _, k := range keys {
+		name := strings.ToLower(strings.TrimSpace(k))
+		descending := false
+
+		if i := strings.LastIndex(name, ":"); i != -1 {
+			switch name[i+1:] {
+			case "asc":
+			case "desc":
+				descending = true
+			default:
+				return fmt.Errorf("\"%s\" is not a valid sort direction, expected \"asc\" or \"desc\"", name[i+1:])
+			}
+
+			name = name[:i]
+		}
+
+		if name == "" {
+			continue
+		}
+
+		column, ok := probeColumns[name]
+
+		if !ok {
+			return fmt.Errorf("\"%s\" is not a valid sort key, expected one of %s", name, strings.Join(ColumnNames(), ", "))
+		}
+
+		sortKeys = append(sortKeys, sortKey{column: column, descending: descending})
+	}
+
+	sort.SliceStable(p.media, func(i, j int) bool {
+		for _, k := range sortKeys {
+			c := compareValues(k.column.sort(p.media[i]), k.column.sort(p.media[j]))
+
+			if c == 0 {
+				continue
+			}
+
+			if k.descending {
+				return c > 0
+			}
+
+			return c < 0
+		}
+
+		return false
+	})
+
+	return nil
+}
+
+func compareValues(a interface{}, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		b := b.(float64)
+
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		default:
+			return 0
+		}
+	default:
+		return strings.Compare(a.(string), b.(string))
+	}
+}
+
+func (p *Probe) cells(m *Media) []probeCell {
+	columns := p.Columns()
+	cells := make([]probeCell, len(columns))
+
+	for k, c := range columns {
+		column := probeColumns[c]
+		sortValue := column.sort(m)
+
+		if f, ok := sortValue.(float64); ok {
+			sortValue = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+
+		cells[k] = probeCell{
+			Lines: column.display(m),
+			Sort:  sortValue.(string),
+		}
+	}
+
+	return cells
+}
+
+func (p *Probe) headers() []string {
+	columns := p.Columns()
+	headers := make([]string, len(columns))
+
+	for k, c := range columns {
+		headers[k] = probeColumns[c].title
+	}
+
+	return headers
+}
+
+// defaultRecordColumns are the columns of the json and csv output when no columns
+// are selected. They are a stable contract, documented in the help of the
+// probe command: columns may be appended, but are never renamed, reordered or
+// removed.
+var defaultRecordColumns = []string{
+	"title",
+	"year",
+	"duration",
+	"rating",
+	"size",
+	"quality",
+	"bitrate",
+	"video_codec",
+	"frame_rate",
+	"audio_codec",
+	"audio_channels",
+	"guid",
+	"rating_key",
+	"media_id",
+	"files",
+	"audio_languages",
+	"subtitle_languages",
+	"dynamic_range",
+	"bit_depth",
+	"type",
+	"artist",
+	"album",
+	"track",
+	"sample_rate",
+	"width",
+	"height",
+	"library",
+	"show",
+	"season",
+	"episode",
+	"episodes",
+	"qualities",
+	"mixed_quality",
+	"dimensions",
+}
+
+// recordColumns returns the selected columns, or every column in the order
+// of the contract when none are selected.
+func (p *Probe) recordColumns() []string {
+	if len(p.columns) > 0 {
+		return p.columns
+	}
+
+	return defaultRecordColumns
+}
+
+// columnRecord is one media's raw column values, encoded as a JSON object
+// whose keys follow the column order.
+type columnRecord struct {
+	columns []string
+	values  map[string]interface{}
+}
+
+func newColumnRecord(m *Media, columns []string) columnRecord {
+	r := columnRecord{columns: columns, values: make(map[string]interface{}, len(columns))}
+
+	for _, c := range columns {
+		r.values[c] = probeColumns[c].raw(m)
+	}
+
+	return r
+}
+
+// with returns a copy of the record with another key appended.
+func (r columnRecord) with(key string, value interface{}) columnRecord {
+	w := columnRecord{
+		columns: append(append(make([]string, 0, len(r.columns)+1), r.columns...), key),
+		values:  make(map[string]interface{}, len(r.values)+1),
+	}
+
+	for k, v := range r.values {
+		w.values[k] = v
+	}
+
+	w.values[key] = value
+
+	return w
+}
+
+func (r columnRecord) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteByte('{')
+
+	for k, c := range r.columns {
+		if k > 0 {
+			b.WriteByte(',')
+		}
+
+		key, err := json.Marshal(c)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(r.values[c])
+
+		if err != nil {
+			return nil, err
+		}
+
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+func (p *Probe) records() []columnRecord {
+	columns := p.recordColumns()
+	records := make([]columnRecord, len(p.media))
+
+	for k, m := range p.media {
+		records[k] = newColumnRecord(m, columns)
+	}
+
+	return records
+}
+
+func formatRaw(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return formatFloat(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []string:
+		return strings.Join(v, ";")
+	default:
+		return fmt.Sprint(v)
+	}
+}
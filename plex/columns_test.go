@@ -0,0 +1,55 @@
+package plex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestJsonKeepsColumnOrder(t *testing.T) {
+	p := &Probe{media: []*Media{{Title: "Movie", Year: 2001, Size: 1000}}}
+
+	if err := p.SetColumns([]string{"year", "title", "size"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+
+	if err := p.Json(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[\n  {\n    \"year\": 2001,\n    \"title\": \"Movie\",\n    \"size\": 1000\n  }\n]\n"
+
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestDefaultRecordsUseIntegerTypes(t *testing.T) {
+	p := &Probe{media: []*Media{{ID: 7, Title: "Movie", Year: 2001, Rating: 7.5, Width: 1920, Height: 1080}}}
+
+	var b bytes.Buffer
+
+	if err := p.Json(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`"title": "Movie"`, `"year": 2001`, `"rating": 7.5`, `"media_id": 7`, `"dimensions": "1920x1080"`} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("expected %s in %s", expected, b.String())
+		}
+	}
+
+	b.Reset()
+
+	if err := p.Csv(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+
+	if !strings.HasPrefix(lines[0], "title,year,duration,rating,size,") || !strings.HasPrefix(lines[1], "Movie,2001,0,7.5,0,") {
+		t.Errorf("unexpected csv output:\n%s", b.String())
+	}
+}
@@ -0,0 +1,281 @@
+package plex
+
+import (
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+const compareTemplate = `<!doctype html>
+<html lang="en">
+	<head>
+		<meta charset="utf-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+		{{stylesheet}}
+		<title>Compare: {{.Left}} vs. {{.Right}}</title>
+	</head>
+	<body>
+		<h1>{{.Left}} vs. {{.Right}}</h1>
+		<input type="search" class="form-control" placeholder="Search" aria-label="Search" data-table="compare">
+		<table id="compare" class="table table-striped table-sm sortable">
+			<thead class="thead-dark">
+				<tr>
+					<th scope="col">Title</th>
+					<th scope="col">Year</th>
+					<th scope="col">Status</th>
+					<th scope="col">Field</th>
+					<th scope="col">{{.Left}}</th>
+					<th scope="col">{{.Right}}</th>
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Comparison.MissingRight}}<tr>
+					<td data-sort="{{.SortTitle}}">{{.Title}}</td>
+					<td>{{.Year}}</td>
+					<td>Missing on right</td>
+					<td></td>
+					<td data-sort="{{.Size}}">{{.HumanizeSize}}</td>
+					<td></td>
+				</tr>{{end}}
+				{{range .Comparison.MissingLeft}}<tr>
+					<td data-sort="{{.SortTitle}}">{{.Title}}</td>
+					<td>{{.Year}}</td>
+					<td>Missing on left</td>
+					<td></td>
+					<td></td>
+					<td data-sort="{{.Size}}">{{.HumanizeSize}}</td>
+				</tr>{{end}}
+				{{range $c := .Comparison.Differences}}{{range .Changes}}<tr>
+					<td data-sort="{{$c.Old.SortTitle}}">{{$c.Old.Title}}</td>
+					<td>{{$c.Old.Year}}</td>
+					<td>Different</td>
+					<td>{{.Field}}</td>
+					<td>{{.Old}}</td>
+					<td>{{.New}}</td>
+				</tr>{{end}}{{end}}
+			</tbody>
+		</table>
+		{{script}}
+	</body>
+</html>`
+
+type Comparison struct {
+	cdn          bool
+	differences  []*MediaChange
+	left         *Probe
+	missingLeft  []*Media
+	missingRight []*Media
+	right        *Probe
+}
+
+func Compare(left *Probe, right *Probe) *Comparison {
+	c := &Comparison{
+		differences:  make([]*MediaChange, 0),
+		left:         left,
+		missingLeft:  make([]*Media, 0),
+		missingRight: make([]*Media, 0),
+		right:        right,
+	}
+
+	leftItems, leftVersions := groupItems(left.media)
+	rightItems, rightVersions := groupItems(right.media)
+	rightIndex := indexItems(rightItems, rightVersions)
+	matched := make(map[string]bool, len(rightItems))
+
+	for _, k := range leftItems {
+		r, ok := rightIndex.lookup(leftVersions[k][0], matched)
+
+		if !ok {
+			c.missingRight = append(c.missingRight, leftVersions[k]...)
+			continue
+		}
+
+		matched[r] = true
+
+		// Media IDs differ between servers, so versions of the same quality
+		// are paired first.
+		missingLeft, missingRight := pairVersions(leftVersions[k], rightVersions[r], func(a *Media, b *Media) bool {
+			return a.Quality == b.Quality
+		}, c.compare)
+
+		c.missingLeft = append(c.missingLeft, missingLeft...)
+		c.missingRight = append(c.missingRight, missingRight...)
+	}
+
+	for _, k := range rightItems {
+		if !matched[k] {
+			c.missingLeft = append(c.missingLeft, rightVersions[k]...)
+		}
+	}
+
+	sort.Slice(c.differences, func(i, j int) bool {
+		return c.differences[i].Old.SortTitle() < c.differences[j].Old.SortTitle()
+	})
+
+	sort.Slice(c.missingLeft, func(i, j int) bool {
+		return c.missingLeft[i].SortTitle() < c.missingLeft[j].SortTitle()
+	})
+
+	sort.Slice(c.missingRight, func(i, j int) bool {
+		return c.missingRight[i].SortTitle() < c.missingRight[j].SortTitle()
+	})
+
+	return c
+}
+
+func (c *Comparison) compare(left *Media, right *Media) {
+	if changes := compareMedia(left, right); len(changes) > 0 {
+		c.differences = append(c.differences, &MediaChange{
+			Changes: changes,
+			New:     right,
+			Old:     left,
+		})
+	}
+}
+
+// groupItems groups media by item, keeping the order in which the items
+// first appear.
+func groupItems(media []*Media) ([]string, map[string][]*Media) {
+	keys := make([]string, 0)
+	versions := make(map[string][]*Media, len(media))
+
+	for _, m := range media {
+		k := itemKey(m)
+
+		if _, ok := versions[k]; !ok {
+			keys = append(keys, k)
+		}
+
+		versions[k] = append(versions[k], m)
+	}
+
+	return keys, versions
+}
+
+type itemIndex struct {
+	guid  map[string][]string
+	title map[string][]string
+}
+
+// indexItems indexes items by GUID and by title and year. GUIDs of items
+// without a match in Plex's agents are local rating keys, which mean nothing
+// on another server, so those items are only indexed by title.
+func indexItems(keys []string, versions map[string][]*Media) *itemIndex {
+	i := &itemIndex{
+		guid:  make(map[string][]string, len(keys)),
+		title: make(map[string][]string, len(keys)),
+	}
+
+	for _, k := range keys {
+		m := versions[k][0]
+
+		if g := comparableGUID(m); g != "" {
+			i.guid[g] = append(i.guid[g], k)
+		}
+
+		i.title[titleKey(m)] = append(i.title[titleKey(m)], k)
+	}
+
+	return i
+}
+
+// lookup returns the first item not yet matched that shares the GUID of m,
+// or failing that its title and year.
+func (i *itemIndex) lookup(m *Media, matched map[string]bool) (string, bool) {
+	if g := comparableGUID(m); g != "" {
+		for _, k := range i.guid[g] {
+			if !matched[k] {
+				return k, true
+			}
+		}
+	}
+
+	for _, k := range i.title[titleKey(m)] {
+		if !matched[k] {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+func comparableGUID(m *Media) string {
+	if strings.HasPrefix(m.GUID, "local://") {
+		return ""
+	}
+
+	return m.GUID
+}
+
+func titleKey(m *Media) string {
+	return fmt.Sprintf("%s|%d", strings.ToLower(m.SortTitle()), m.Year)
+}
+
+func (c *Comparison) Differences() []*MediaChange {
+	return c.differences
+}
+
+func (c *Comparison) MissingLeft() []*Media {
+	return c.missingLeft
+}
+
+func (c *Comparison) MissingRight() []*Media {
+	return c.missingRight
+}
+
+func (c *Comparison) SetCDN(enabled bool) {
+	c.cdn = enabled
+}
+
+func (c *Comparison) Ascii(w io.Writer) {
+	t := tablewriter.NewWriter(w)
+	left := probeName(c.left)
+	right := probeName(c.right)
+
+	t.SetHeader([]string{"Title", "Status", "Field", left, right})
+
+	for _, m := range c.missingRight {
+		t.Append([]string{m.Title, "Missing on right", "", m.HumanizeSize(), ""})
+	}
+
+	for _, m := range c.missingLeft {
+		t.Append([]string{m.Title, "Missing on left", "", "", m.HumanizeSize()})
+	}
+
+	for _, d := range c.differences {
+		for _, f := range d.Changes {
+			t.Append([]string{d.Old.Title, "Different", f.Field, f.Old, f.New})
+		}
+	}
+
+	t.Render()
+}
+
+func (c *Comparison) Html(w io.Writer) error {
+	tmpl, err := template.New("compare").Funcs(htmlFuncs(c.cdn)).Parse(compareTemplate)
+
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, struct {
+		Comparison *Comparison
+		Left       string
+		Right      string
+	}{
+		Comparison: c,
+		Left:       probeName(c.left),
+		Right:      probeName(c.right),
+	})
+}
+
+func probeName(p *Probe) string {
+	if p.server == nil {
+		return p.library
+	}
+
+	return fmt.Sprintf("%s @ %s", p.library, p.server.Name)
+}
@@ -0,0 +1,51 @@
+package plex
+
+import (
+	"testing"
+)
+
+func TestCompareSkipsLocalGUIDs(t *testing.T) {
+	left := &Probe{media: []*Media{{ID: 1, RatingKey: "5", GUID: "local://5", Title: "Home Video", Quality: "1080p"}}}
+	right := &Probe{media: []*Media{{ID: 9, RatingKey: "5", GUID: "local://5", Title: "Totally Different", Quality: "SD"}}}
+
+	c := Compare(left, right)
+
+	if len(c.Differences()) != 0 {
+		t.Errorf("expected no differences, got %+v", c.Differences())
+	}
+
+	if len(c.MissingRight()) != 1 || c.MissingRight()[0].Title != "Home Video" {
+		t.Errorf("expected \"Home Video\" to be missing on the right, got %+v", c.MissingRight())
+	}
+
+	if len(c.MissingLeft()) != 1 || c.MissingLeft()[0].Title != "Totally Different" {
+		t.Errorf("expected \"Totally Different\" to be missing on the left, got %+v", c.MissingLeft())
+	}
+}
+
+func TestComparePairsVersions(t *testing.T) {
+	left := &Probe{media: []*Media{
+		{ID: 1, RatingKey: "10", GUID: "com.plexapp.agents.imdb://tt1", Title: "Movie", Year: 2001, Quality: "1080p", VideoCodec: "h264", Size: 2000},
+		{ID: 2, RatingKey: "10", GUID: "com.plexapp.agents.imdb://tt1", Title: "Movie", Year: 2001, Quality: "SD", VideoCodec: "mpeg4", Size: 500},
+		{ID: 3, RatingKey: "11", Title: "Other", Year: 2002, Quality: "720p", Size: 1000},
+	}}
+	right := &Probe{media: []*Media{
+		{ID: 7, RatingKey: "70", GUID: "com.plexapp.agents.imdb://tt1", Title: "Movie", Year: 2001, Quality: "1080p", VideoCodec: "h264", Size: 2000},
+		{ID: 8, RatingKey: "71", Title: "Other", Year: 2002, Quality: "1080p", Size: 3000},
+		{ID: 9, RatingKey: "71", Title: "Other", Year: 2002, Quality: "SD", Size: 300},
+	}}
+
+	c := Compare(left, right)
+
+	if len(c.MissingRight()) != 1 || c.MissingRight()[0].ID != 2 {
+		t.Errorf("expected only the SD version of \"Movie\" to be missing on the right, got %+v", c.MissingRight())
+	}
+
+	if len(c.MissingLeft()) != 1 || c.MissingLeft()[0].ID != 9 {
+		t.Errorf("expected only the SD version of \"Other\" to be missing on the left, got %+v", c.MissingLeft())
+	}
+
+	if len(c.Differences()) != 1 || c.Differences()[0].Old.ID != 3 || c.Differences()[0].New.ID != 8 {
+		t.Fatalf("expected only \"Other\" to differ, got %+v", c.Differences())
+	}
+}
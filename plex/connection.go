@@ -0,0 +1,129 @@
+package plex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"time"
+)
+
+const connectionTimeout = 3 * time.Second
+
+func (c Connection) Kind() string {
+	switch {
+	case c.Relay == 1:
+		return "relay"
+	case c.Local == 1:
+		return "local"
+	default:
+		return "remote"
+	}
+}
+
+func (p *Plex) connectionCandidates(server *Server) ([]string, error) {
+	if strings.HasPrefix(p.connection, "http://") || strings.HasPrefix(p.connection, "https://") {
+		return []string{p.connection}, nil
+	}
+
+	switch p.connection {
+	case "", "local", "relay", "remote":
+	default:
+		return nil, fmt.Errorf("\"%s\" is not a valid connection, expected a URL, \"local\", \"remote\" or \"relay\"", p.connection)
+	}
+
+	rank := map[string]int{"local": 0, "remote": 1, "relay": 2}
+
+	if !p.preferLocal {
+		rank["local"], rank["remote"] = rank["remote"], rank["local"]
+	}
+
+	connections := make([]Connection, 0, len(server.Connection))
+
+	for _, c := range server.Connection {
+		if p.connection == "" || p.connection == c.Kind() {
+			connections = append(connections, c)
+		}
+	}
+
+	sort.SliceStable(connections, func(i, j int) bool {
+		return rank[connections[i].Kind()] < rank[connections[j].Kind()]
+	})
+
+	candidates := make([]string, len(connections))
+
+	for k, c := range connections {
+		candidates[k] = c.URI
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("server \"%s\" has no matching connections", server.Name)
+	}
+
+	return candidates, nil
+}
+
+// firstReachable probes all candidates at once and returns the best-ranked
+// one that answers, as soon as every candidate ranked above it has failed.
+func (p *Plex) firstReachable(token string, candidates []string) (string, error) {
+	type result struct {
+		k  int
+		ok bool
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	client := &http.Client{Timeout: connectionTimeout}
+	results := make(chan result, len(candidates))
+
+	for k, uri := range candidates {
+		go func(k int, uri string) {
+			req, err := http.NewRequest("GET", strings.TrimRight(uri, "/")+"/identity", nil)
+
+			if err != nil {
+				results <- result{k: k}
+				return
+			}
+
+			req = req.WithContext(ctx)
+			req.Header.Set("X-Plex-Token", token)
+
+			resp, err := client.Do(req)
+
+			if err != nil {
+				results <- result{k: k}
+				return
+			}
+
+			_ = resp.Body.Close()
+			results <- result{k: k, ok: resp.StatusCode == http.StatusOK}
+		}(k, uri)
+	}
+
+	answered := make([]bool, len(candidates))
+	failed := make([]bool, len(candidates))
+
+	for range candidates {
+		r := <-results
+
+		answered[r.k] = r.ok
+		failed[r.k] = !r.ok
+
+		for k := range candidates {
+			if answered[k] {
+				return strings.TrimRight(candidates[k], "/"), nil
+			}
+
+			if !failed[k] {
+				break
+			}
+		}
+	}
+
+	return "", errors.New("no connection answered")
+}
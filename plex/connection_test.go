@@ -0,0 +1,72 @@
+package plex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func identityServer(status int, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+
+		w.WriteHeader(status)
+	}))
+}
+
+func TestFirstReachablePrefersBestRanked(t *testing.T) {
+	best := identityServer(http.StatusOK, 200*time.Millisecond)
+	other := identityServer(http.StatusOK, 0)
+
+	defer best.Close()
+	defer other.Close()
+
+	uri, err := (&Plex{}).firstReachable("token", []string{best.URL, other.URL})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri != best.URL {
+		t.Errorf("expected \"%s\", got \"%s\"", best.URL, uri)
+	}
+}
+
+func TestFirstReachableDoesNotWaitForLowerRanked(t *testing.T) {
+	failing := identityServer(http.StatusUnauthorized, 0)
+	answering := identityServer(http.StatusOK, 0)
+	slow := identityServer(http.StatusOK, 2*time.Second)
+
+	defer failing.Close()
+	defer answering.Close()
+	defer slow.Close()
+
+	started := time.Now()
+	uri, err := (&Plex{}).firstReachable("token", []string{failing.URL, answering.URL, slow.URL})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uri != answering.URL {
+		t.Errorf("expected \"%s\", got \"%s\"", answering.URL, uri)
+	}
+
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected an early return, took %s", elapsed)
+	}
+}
+
+func TestFirstReachableFailsWhenNoneAnswer(t *testing.T) {
+	failing := identityServer(http.StatusUnauthorized, 0)
+
+	defer failing.Close()
+
+	if _, err := (&Plex{}).firstReachable("token", []string{failing.URL, "http://127.0.0.1:1"}); err == nil {
+		t.Error("expected an error when no connection answers")
+	}
+}
@@ -0,0 +1,381 @@
+package plex
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const duplicatesTemplate = `<!doctype html>
+<html lang="en">
+	<head>
+		<meta charset="utf-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+		{{stylesheet}}
+		<title>Duplicates: {{.Probe.Library}} @ {{.Probe.Server.Name}}</title>
+	</head>
+	<body>
+		<h1>{{.Probe.Library}} @ {{.Probe.Server.Name}}</h1>
+		<dl class="summary">
+			<div>
+				<dt>Duplicates</dt>
+				<dd>{{len .Duplicates.Sets}}</dd>
+			</div>
+			<div>
+				<dt>Copies</dt>
+				<dd>{{.Duplicates.Copies}}</dd>
+			</div>
+			<div>
+				<dt>Reclaimable</dt>
+				<dd>{{.Duplicates.HumanizeReclaimable}}</dd>
+			</div>
+		</dl>
+		<input type="search" class="form-control" placeholder="Search" aria-label="Search" data-table="duplicates">
+		<table id="duplicates" class="table table-striped table-sm">
+			<thead class="thead-dark">
+				<tr>
+					<th scope="col">Title</th>
+					<th scope="col">Reason</th>
+					<th scope="col">Library</th>
+					<th scope="col">Quality</th>
+					<th scope="col">Size</th>
+					<th scope="col">Bit Rate</th>
+					<th scope="col">File</th>
+					<th scope="col">Keep</th>
+				</tr>
+			</thead>
+			<tbody>
+				{{range $s := .Duplicates.Sets}}{{range .Copies}}<tr>
+					<td>{{$s.Title}}</td>
+					<td>{{$s.Reason}}</td>
+					<td>{{.Library}}</td>
+					<td>{{.Quality}}</td>
+					<td>{{.HumanizeSize}}</td>
+					<td>{{.HumanizeBitRate}}</td>
+					<td>{{range $i, $f := .Files}}{{if $i}}<br>{{end}}{{$f}}{{end}}</td>
+					<td>{{if $s.Keep .}}yes{{end}}</td>
+				</tr>{{end}}{{end}}
+			</tbody>
+		</table>
+		{{script}}
+	</body>
+</html>`
+
+type Duplicates struct {
+	probe *Probe
+	sets  []*DuplicateSet
+}
+
+// DuplicateSet is one movie or episode that is stored more than once, either
+// as several versions of the same item or as separate items.
+type DuplicateSet struct {
+	best   *Media
+	copies []*Media
+}
+
+// Duplicates finds items with more than one version, and separate items that
+// share a GUID. Items without a GUID from Plex's agents are matched by their
+// title and year, or their show, season and episode number, instead.
+func (p *Probe) Duplicates() *Duplicates {
+	parents := make([]int, len(p.media))
+
+	for k := range parents {
+		parents[k] = k
+	}
+
+	var find func(k int) int
+
+	find = func(k int) int {
+		if parents[k] != k {
+			parents[k] = find(parents[k])
+		}
+
+		return parents[k]
+	}
+
+	seen := make(map[string]int, 0)
+
+	for k, m := range p.media {
+		for _, key := range duplicateKeys(m) {
+			if other, ok := seen[key]; ok {
+				parents[find(k)] = find(other)
+			} else {
+				seen[key] = k
+			}
+		}
+	}
+
+	members := make(map[int][]*Media, 0)
+	roots := make([]int, 0)
+
+	for k, m := range p.media {
+		root := find(k)
+
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+
+		members[root] = append(members[root], m)
+	}
+
+	d := &Duplicates{probe: p, sets: make([]*DuplicateSet, 0)}
+
+	for _, root := range roots {
+		if len(members[root]) < 2 {
+			continue
+		}
+
+		s := &DuplicateSet{copies: members[root]}
+
+		sort.SliceStable(s.copies, func(i, j int) bool {
+			return betterCopy(s.copies[i], s.copies[j])
+		})
+
+		s.best = s.copies[0]
+		d.sets = append(d.sets, s)
+	}
+
+	sort.SliceStable(d.sets, func(i, j int) bool {
+		if d.sets[i].Reclaimable() != d.sets[j].Reclaimable() {
+			return d.sets[i].Reclaimable() > d.sets[j].Reclaimable()
+		}
+
+		return d.sets[i].best.SortTitle() < d.sets[j].best.SortTitle()
+	})
+
+	return d
+}
+
+func duplicateKeys(m *Media) []string {
+	keys := make([]string, 0)
+
+	if m.RatingKey != "" {
+		keys = append(keys, "rating_key|"+m.RatingKey)
+	}
+
+	// Items with different GUIDs are different movies or episodes, even if
+	// they share a title.
+	if g := comparableGUID(m); g != "" {
+		return append(keys, "guid|"+g)
+	}
+
+	switch {
+	case m.Type == "episode" && m.Episode > 0:
+		keys = append(keys, fmt.Sprintf("episode|%s|s%02de%02d", strings.ToLower(m.Show), m.Season, m.Episode))
+	case m.Type == "movie":
+		keys = append(keys, "movie|"+titleKey(m))
+	}
+
+	return keys
+}
+
+// betterCopy reports whether a is worth keeping over b, preferring the higher
+// resolution, then the higher bit rate and then the larger file.
+func betterCopy(a *Media, b *Media) bool {
+	if qualityRank(a.Quality) != qualityRank(b.Quality) {
+		return qualityRank(a.Quality) > qualityRank(b.Quality)
+	}
+
+	if a.Bitrate != b.Bitrate {
+		return a.Bitrate > b.Bitrate
+	}
+
+	return a.Size > b.Size
+}
+
+func (d *Duplicates) Copies() int {
+	copies := 0
+
+	for _, s := range d.sets {
+		copies += len(s.copies)
+	}
+
+	return copies
+}
+
+func (d *Duplicates) HumanizeReclaimable() string {
+	return humanize.Bytes(d.Reclaimable())
+}
+
+func (d *Duplicates) Reclaimable() uint64 {
+	var size uint64
+
+	for _, s := range d.sets {
+		size += s.Reclaimable()
+	}
+
+	return size
+}
+
+func (d *Duplicates) Sets() []*DuplicateSet {
+	return d.sets
+}
+
+func (s *DuplicateSet) Best() *Media {
+	return s.best
+}
+
+func (s *DuplicateSet) Copies() []*Media {
+	return s.copies
+}
+
+func (s *DuplicateSet) Keep(m *Media) bool {
+	return m == s.best
+}
+
+// Reason is "versions" when every copy belongs to the same Plex item, and
+// "items" when the copies are separate items.
+func (s *DuplicateSet) Reason() string {
+	for _, m := range s.copies {
+		if m.RatingKey != s.best.RatingKey {
+			return "items"
+		}
+	}
+
+	return "versions"
+}
+
+func (s *DuplicateSet) Reclaimable() uint64 {
+	var size uint64
+
+	for _, m := range s.copies {
+		if m != s.best {
+			size += m.Size
+		}
+	}
+
+	return size
+}
+
+func (s *DuplicateSet) Title() string {
+	if s.best.Type == "movie" && s.best.Year > 0 {
+		return fmt.Sprintf("%s (%d)", s.best.Title, s.best.Year)
+	}
+
+	return s.best.Title
+}
+
+func (d *Duplicates) Ascii(w io.Writer) {
+	t := tablewriter.NewWriter(w)
+
+	t.SetHeader([]string{"Title", "Reason", "Library", "Quality", "Size", "Bit Rate", "File", "Keep"})
+
+	for _, s := range d.sets {
+		for k, m := range s.copies {
+			title := ""
+			reason := ""
+			keep := ""
+
+			if k == 0 {
+				title = s.Title()
+				reason = s.Reason()
+			}
+
+			if s.Keep(m) {
+				keep = "yes"
+			}
+
+			t.Append([]string{title, reason, m.Library, m.Quality, m.HumanizeSize(), m.HumanizeBitRate(), strings.Join(m.Files(), "\n"), keep})
+		}
+	}
+
+	t.SetFooter([]string{"", "", "", "", "", "", "Reclaimable", d.HumanizeReclaimable()})
+	t.Render()
+}
+
+func (d *Duplicates) Csv(w io.Writer) error {
+	c := csv.NewWriter(w)
+
+	if err := c.Write([]string{"title", "reason", "reclaimable", "keep", "library", "quality", "size", "bitrate", "rating_key", "media_id", "files"}); err != nil {
+		return err
+	}
+
+	for _, s := range d.sets {
+		for _, m := range s.copies {
+			err := c.Write([]string{
+				s.Title(),
+				s.Reason(),
+				strconv.FormatUint(s.Reclaimable(), 10),
+				strconv.FormatBool(s.Keep(m)),
+				m.Library,
+				m.Quality,
+				strconv.FormatUint(m.Size, 10),
+				strconv.FormatUint(m.Bitrate, 10),
+				m.RatingKey,
+				strconv.Itoa(m.ID),
+				strings.Join(m.Files(), ";"),
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Flush()
+
+	return c.Error()
+}
+
+func (d *Duplicates) Html(w io.Writer) error {
+	tmpl, err := template.New("duplicates").Funcs(htmlFuncs(d.probe.cdn)).Parse(duplicatesTemplate)
+
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, struct {
+		Duplicates *Duplicates
+		Probe      *Probe
+	}{
+		Duplicates: d,
+		Probe:      d.probe,
+	})
+}
+
+type duplicateReport struct {
+	Copies      []columnRecord `json:"copies"`
+	Reason      string         `json:"reason"`
+	Reclaimable uint64         `json:"reclaimable"`
+	Title       string         `json:"title"`
+}
+
+func (d *Duplicates) Json(w io.Writer) error {
+	e := json.NewEncoder(w)
+
+	e.SetIndent("", "  ")
+
+	sets := make([]duplicateReport, len(d.sets))
+
+	for k, s := range d.sets {
+		sets[k] = duplicateReport{
+			Copies:      make([]columnRecord, len(s.copies)),
+			Reason:      s.Reason(),
+			Reclaimable: s.Reclaimable(),
+			Title:       s.Title(),
+		}
+
+		for i, m := range s.copies {
+			sets[k].Copies[i] = newColumnRecord(m, defaultRecordColumns).with("keep", s.Keep(m))
+		}
+	}
+
+	return e.Encode(struct {
+		Duplicates  []duplicateReport `json:"duplicates"`
+		Library     string            `json:"library"`
+		Reclaimable uint64            `json:"reclaimable"`
+		Server      string            `json:"server"`
+	}{
+		Duplicates:  sets,
+		Library:     d.probe.Library(),
+		Reclaimable: d.Reclaimable(),
+		Server:      d.probe.Server().Name,
+	})
+}
@@ -0,0 +1,76 @@
+package plex
+
+import (
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	p := &Probe{media: []*Media{
+		{ID: 1, RatingKey: "10", GUID: "com.plexapp.agents.imdb://tt1", Type: "movie", Title: "Movie", Year: 2001, Quality: "720p", Bitrate: 4000, Size: 1000},
+		{ID: 2, RatingKey: "10", GUID: "com.plexapp.agents.imdb://tt1", Type: "movie", Title: "Movie", Year: 2001, Quality: "1080p", Bitrate: 2000, Size: 3000},
+		{ID: 3, RatingKey: "20", GUID: "local://20", Type: "movie", Title: "Home Video", Year: 2010, Quality: "1080p", Bitrate: 2000, Size: 400},
+		{ID: 4, RatingKey: "21", GUID: "local://21", Type: "movie", Title: "Home Video", Year: 2010, Quality: "1080p", Bitrate: 3000, Size: 300},
+		{ID: 5, RatingKey: "30", GUID: "com.plexapp.agents.imdb://tt2", Type: "movie", Title: "Remake", Year: 2005, Quality: "1080p", Size: 5000},
+		{ID: 6, RatingKey: "31", GUID: "com.plexapp.agents.imdb://tt3", Type: "movie", Title: "Remake", Year: 2005, Quality: "1080p", Size: 5000},
+		{ID: 7, RatingKey: "40", GUID: "com.plexapp.agents.thetvdb://1/1/1", Type: "episode", Show: "Show", Season: 1, Episode: 1, Title: "Pilot", Size: 100},
+		{ID: 8, RatingKey: "41", GUID: "com.plexapp.agents.thetvdb://1/1/2", Type: "episode", Show: "Show", Season: 1, Episode: 1, Title: "Pilot", Size: 100},
+		{ID: 9, RatingKey: "50", Type: "episode", Show: "Show", Season: 2, Episode: 3, Title: "Third", Quality: "SD", Size: 200},
+		{ID: 10, RatingKey: "51", Type: "episode", Show: "show", Season: 2, Episode: 3, Title: "Third", Quality: "4k", Size: 800},
+	}}
+
+	d := p.Duplicates()
+	sets := d.Sets()
+
+	if len(sets) != 3 {
+		t.Fatalf("expected 3 duplicates, got %d", len(sets))
+	}
+
+	tests := []struct {
+		title       string
+		reason      string
+		best        int
+		copies      int
+		reclaimable uint64
+	}{
+		{"Movie (2001)", "versions", 2, 2, 1000},
+		{"Home Video (2010)", "items", 4, 2, 400},
+		{"Third", "items", 10, 2, 200},
+	}
+
+	for k, test := range tests {
+		s := sets[k]
+
+		if s.Title() != test.title {
+			t.Errorf("expected duplicate %d to be \"%s\", got \"%s\"", k, test.title, s.Title())
+			continue
+		}
+
+		if s.Reason() != test.reason {
+			t.Errorf("%s: expected reason \"%s\", got \"%s\"", test.title, test.reason, s.Reason())
+		}
+
+		if s.Best().ID != test.best {
+			t.Errorf("%s: expected media %d to be kept, got %d", test.title, test.best, s.Best().ID)
+		}
+
+		if !s.Keep(s.Best()) {
+			t.Errorf("%s: expected the best copy to be kept", test.title)
+		}
+
+		if len(s.Copies()) != test.copies {
+			t.Errorf("%s: expected %d copies, got %d", test.title, test.copies, len(s.Copies()))
+		}
+
+		if s.Reclaimable() != test.reclaimable {
+			t.Errorf("%s: expected %d reclaimable bytes, got %d", test.title, test.reclaimable, s.Reclaimable())
+		}
+	}
+
+	if d.Copies() != 6 {
+		t.Errorf("expected 6 copies, got %d", d.Copies())
+	}
+
+	if d.Reclaimable() != 1600 {
+		t.Errorf("expected 1600 reclaimable bytes, got %d", d.Reclaimable())
+	}
+}
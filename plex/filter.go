@@ -0,0 +1,498 @@
+package plex
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type Filter struct {
+	expression string
+	root       filterNode
+}
+
+type filterNode interface {
+	match(m *Media) bool
+}
+
+type filterAnd struct {
+	left  filterNode
+	right filterNode
+}
+
+type filterComparison struct {
+	field    filterField
+	number   float64
+	operator string
+	text     string
+}
+
+// filterField reads a field of media as text, as a number or, for fields
+// with several values such as languages, as a list. A list matches "==" and
+// "~" if any of its values does, and "!=" if none of them is equal.
+type filterField struct {
+	kind   string
+	list   func(m *Media) []string
+	number func(m *Media) float64
+	text   func(m *Media) string
+}
+
+type filterNot struct {
+	node filterNode
+}
+
+type filterOr struct {
+	left  filterNode
+	right filterNode
+}
+
+type filterParser struct {
+	position int
+	tokens   []filterToken
+}
+
+type filterToken struct {
+	kind  string
+	value string
+}
+
+var filterFields = map[string]filterField{
+	"album":              {kind: "string", text: func(m *Media) string { return m.Album }},
+	"artist":             {kind: "string", text: func(m *Media) string { return m.Artist }},
+	"audio_channels":     {kind: "number", number: func(m *Media) float64 { return float64(m.AudioChannels) }},
+	"audio_codec":        {kind: "string", text: func(m *Media) string { return m.AudioCodec }},
+	"audio_languages":    {kind: "list", list: func(m *Media) []string { return m.AudioLanguages() }},
+	"bit_depth":          {kind: "number", number: func(m *Media) float64 { return float64(m.BitDepth()) }},
+	"bitrate":            {kind: "bitrate", number: func(m *Media) float64 { return float64(m.Bitrate) }},
+	"dimensions":         {kind: "string", text: (*Media).Dimensions},
+	"duration":           {kind: "duration", number: func(m *Media) float64 { return float64(m.Duration) }},
+	"dynamic_range":      {kind: "string", text: func(m *Media) string { return m.DynamicRange() }},
+	"episode":            {kind: "number", number: func(m *Media) float64 { return float64(m.Episode) }},
+	"episodes":           {kind: "number", number: func(m *Media) float64 { return float64(m.Episodes) }},
+	"files":              {kind: "list", list: (*Media).Files},
+	"frame_rate":         {kind: "string", text: func(m *Media) string { return m.FrameRate }},
+	"guid":               {kind: "string", text: func(m *Media) string { return m.GUID }},
+	"height":             {kind: "number", number: func(m *Media) float64 { return float64(m.Height) }},
+	"library":            {kind: "string", text: func(m *Media) string { return m.Library }},
+	"media_id":           {kind: "number", number: func(m *Media) float64 { return float64(m.ID) }},
+	"qualities":          {kind: "list", list: func(m *Media) []string { return m.Qualities }},
+	"quality":            {kind: "quality", number: func(m *Media) float64 { return qualityRank(m.Quality) }},
+	"rating":             {kind: "number", number: func(m *Media) float64 { return m.Rating }},
+	"rating_key":         {kind: "string", text: func(m *Media) string { return m.RatingKey }},
+	"season":             {kind: "number", number: func(m *Media) float64 { return float64(m.Season) }},
+	"sample_rate":        {kind: "number", number: func(m *Media) float64 { return float64(m.SamplingRate()) }},
+	"show":               {kind: "string", text: func(m *Media) string { return m.Show }},
+	"size":               {kind: "size", number: func(m *Media) float64 { return float64(m.Size) }},
+	"subtitle_languages": {kind: "list", list: func(m *Media) []string { return m.SubtitleLanguages() }},
+	"title":              {kind: "string", text: func(m *Media) string { return m.Title }},
+	"track":              {kind: "number", number: func(m *Media) float64 { return float64(m.Track) }},
+	"type":               {kind: "string", text: func(m *Media) string { return m.Type }},
+	"video_codec":        {kind: "string", text: func(m *Media) string { return m.VideoCodec }},
+	"width":              {kind: "number", number: func(m *Media) float64 { return float64(m.Width) }},
+	"year":               {kind: "number", number: func(m *Media) float64 { return float64(m.Year) }},
+}
+
+var filterKeywords = map[string]string{
+	"!":   "not",
+	"&&":  "and",
+	"and": "and",
+	"not": "not",
+	"or":  "or",
+	"||":  "or",
+}
+
+func ParseFilter(expression string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("filter \"%s\" is empty", expression)
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter \"%s\": %s", expression, err)
+	}
+
+	if p.position < len(p.tokens) {
+		return nil, fmt.Errorf("invalid filter \"%s\": unexpected \"%s\"", expression, p.tokens[p.position].value)
+	}
+
+	return &Filter{expression: expression, root: root}, nil
+}
+
+func tokenizeFilter(expression string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+	runes := []rune(expression)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, filterToken{kind: string(r), value: string(r)})
+			i++
+		case r == '"' || r == '\'':
+			j := i + 1
+
+			for j < len(runes) && runes[j] != r {
+				j++
+			}
+
+			if j == len(runes) {
+				return nil, fmt.Errorf("unterminated string in filter \"%s\"", expression)
+			}
+
+			tokens = append(tokens, filterToken{kind: "string", value: string(runes[i+1 : j])})
+			i = j + 1
+		case strings.ContainsRune("<>=!~&|", r):
+			j := i + 1
+
+			for j < len(runes) && strings.ContainsRune("<>=&|", runes[j]) {
+				j++
+			}
+
+			value := string(runes[i:j])
+
+			if keyword, ok := filterKeywords[value]; ok {
+				tokens = append(tokens, filterToken{kind: keyword, value: value})
+			} else {
+				tokens = append(tokens, filterToken{kind: "operator", value: value})
+			}
+
+			i = j
+		default:
+			j := i
+
+			for j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()<>=!~&|\"'", runes[j]) {
+				j++
+			}
+
+			value := string(runes[i:j])
+
+			if keyword, ok := filterKeywords[strings.ToLower(value)]; ok {
+				tokens = append(tokens, filterToken{kind: keyword, value: value})
+			} else {
+				tokens = append(tokens, filterToken{kind: "word", value: value})
+			}
+
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
+
+func (p *filterParser) next() (filterToken, bool) {
+	if p.position >= len(p.tokens) {
+		return filterToken{}, false
+	}
+
+	t := p.tokens[p.position]
+	p.position++
+
+	return t, true
+}
+
+func (p *filterParser) peek(kind string) bool {
+	return p.position < len(p.tokens) && p.tokens[p.position].kind == kind
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek("or") {
+		p.position++
+
+		right, err := p.parseAnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = filterOr{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek("and") {
+		p.position++
+
+		right, err := p.parseNot()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = filterAnd{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.peek("not") {
+		p.position++
+
+		node, err := p.parseNot()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return filterNot{node: node}, nil
+	}
+
+	if p.peek("(") {
+		p.position++
+
+		node, err := p.parseOr()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing \")\"")
+		}
+
+		p.position++
+
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	name, ok := p.next()
+
+	if !ok {
+		return nil, fmt.Errorf("expected a field name")
+	}
+
+	if name.kind != "word" {
+		return nil, fmt.Errorf("expected a field name, got \"%s\"", name.value)
+	}
+
+	field, ok := filterFields[strings.ToLower(name.value)]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown field \"%s\"", name.value)
+	}
+
+	operator, ok := p.next()
+
+	if !ok || operator.kind != "operator" {
+		return nil, fmt.Errorf("expected an operator after \"%s\"", name.value)
+	}
+
+	switch operator.value {
+	case "==", "!=", "<", "<=", ">", ">=", "~":
+	case "=":
+		operator.value = "=="
+	default:
+		return nil, fmt.Errorf("unknown operator \"%s\"", operator.value)
+	}
+
+	value, ok := p.next()
+
+	if !ok || (value.kind != "word" && value.kind != "string") {
+		return nil, fmt.Errorf("expected a value after \"%s %s\"", name.value, operator.value)
+	}
+
+	c := filterComparison{field: field, operator: operator.value, text: strings.ToLower(value.value)}
+
+	if field.kind == "string" || field.kind == "list" {
+		if operator.value != "==" && operator.value != "!=" && operator.value != "~" {
+			return nil, fmt.Errorf("operator \"%s\" is not supported for \"%s\"", operator.value, name.value)
+		}
+
+		return c, nil
+	}
+
+	if operator.value == "~" {
+		return nil, fmt.Errorf("operator \"~\" is not supported for \"%s\"", name.value)
+	}
+
+	number, err := parseFilterValue(field.kind, value.value)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid value \"%s\" for \"%s\": %s", value.value, name.value, err)
+	}
+
+	c.number = number
+
+	return c, nil
+}
+
+func parseFilterValue(kind string, value string) (float64, error) {
+	switch kind {
+	case "bitrate":
+		lower := strings.ToLower(value)
+
+		for _, suffix := range []string{"bps", "b/s", "ps", "/s"} {
+			if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+				value = value[:len(value)-len(suffix)]
+
+				break
+			}
+		}
+
+		bitrate, err := humanize.ParseBytes(value)
+
+		return float64(bitrate), err
+	case "duration":
+		if minutes, err := strconv.ParseFloat(value, 64); err == nil {
+			return float64(time.Duration(minutes * float64(time.Minute))), nil
+		}
+
+		duration, err := time.ParseDuration(value)
+
+		return float64(duration), err
+	case "quality":
+		if rank := qualityRank(value); rank > 0 {
+			return rank, nil
+		}
+
+		return 0, fmt.Errorf("expected a resolution such as 720p, 1080p or 4k")
+	case "size":
+		size, err := humanize.ParseBytes(value)
+
+		return float64(size), err
+	default:
+		return strconv.ParseFloat(value, 64)
+	}
+}
+
+func qualityRank(quality string) float64 {
+	quality = strings.ToLower(strings.TrimSpace(quality))
+
+	switch quality {
+	case "sd":
+		return 480
+	case "4k":
+		return 2160
+	case "8k":
+		return 4320
+	}
+
+	rank, err := strconv.Atoi(strings.TrimSuffix(quality, "p"))
+
+	if err != nil {
+		return 0
+	}
+
+	return float64(rank)
+}
+
+func (n filterAnd) match(m *Media) bool {
+	return n.left.match(m) && n.right.match(m)
+}
+
+func (n filterComparison) match(m *Media) bool {
+	switch n.field.kind {
+	case "list":
+		for _, value := range n.field.list(m) {
+			if n.matchText(value) {
+				return n.operator != "!="
+			}
+		}
+
+		return n.operator == "!="
+	case "string":
+		if n.operator == "!=" {
+			return !n.matchText(n.field.text(m))
+		}
+
+		return n.matchText(n.field.text(m))
+	}
+
+	actual := n.field.number(m)
+
+	switch n.operator {
+	case "==":
+		return actual == n.number
+	case "!=":
+		return actual != n.number
+	case "<":
+		return actual < n.number
+	case "<=":
+		return actual <= n.number
+	case ">":
+		return actual > n.number
+	default:
+		return actual >= n.number
+	}
+}
+
+// matchText reports whether a value is equal to, or for "~" contains, the
+// text of the comparison. "!=" is matched as the negation of "==".
+func (n filterComparison) matchText(value string) bool {
+	value = strings.ToLower(value)
+
+	if n.operator == "~" {
+		return strings.Contains(value, n.text)
+	}
+
+	return value == n.text
+}
+
+func (n filterNot) match(m *Media) bool {
+	return !n.node.match(m)
+}
+
+func (n filterOr) match(m *Media) bool {
+	return n.left.match(m) || n.right.match(m)
+}
+
+func (f *Filter) Match(m *Media) bool {
+	return f.root.match(m)
+}
+
+func (f *Filter) String() string {
+	return f.expression
+}
+
+func (p *Probe) Filter(f *Filter) *Probe {
+	filtered := *p
+	filtered.media = make([]*Media, 0, len(p.media))
+
+	for _, m := range p.media {
+		if f.Match(m) {
+			filtered.media = append(filtered.media, m)
+		}
+	}
+
+	if len(p.libraries) > 0 {
+		filtered.libraries = make([]*Probe, len(p.libraries))
+
+		for k, l := range p.libraries {
+			filtered.libraries[k] = l.Filter(f)
+		}
+	}
+
+	return &filtered
+}
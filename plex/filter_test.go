@@ -0,0 +1,107 @@
+package plex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	movie := &Media{
+		AudioStreams:    []*Stream{{LanguageCode: "swe"}, {LanguageCode: "fin"}},
+		Bitrate:         3000000,
+		Duration:        100 * time.Minute,
+		Episodes:        0,
+		Height:          1080,
+		Parts:           []*Part{{File: "/movies/Movie (2001)/Movie.mkv"}},
+		Quality:         "1080p",
+		Size:            25000000000,
+		SubtitleStreams: []*Stream{{LanguageCode: "eng"}},
+		Title:           "Movie",
+		Width:           1920,
+		Year:            2001,
+	}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{"size > 20GB", true},
+		{"size > 30GB", false},
+		{"bitrate >= 2Mbps", true},
+		{"bitrate > 3mb/s", false},
+		{"duration > 1h30m", true},
+		{"duration > 90", true},
+		{"duration >= 101", false},
+		{"quality > SD", true},
+		{"quality >= 4k", false},
+		{"quality = 1080p", true},
+		{"quality < 1080", false},
+		{"title = movie", true},
+		{"title != 'Movie'", false},
+		{"title ~ ov", true},
+		{"dimensions = 1920x1080", true},
+		{"episodes = 0", true},
+		{"files ~ \"Movie (2001)\"", true},
+		{"audio_languages = fin", true},
+		{"audio_languages != eng", true},
+		{"audio_languages != swe", false},
+		{"subtitle_languages = eng", true},
+		{"year = 2001 or year = 2002 and quality = 4k", true},
+		{"(year = 2001 or year = 2002) and quality = 4k", false},
+		{"year = 2002 and quality = 4k or title = movie", true},
+		{"not year = 2001 or title = movie", true},
+		{"not (year = 2001 or title = movie)", false},
+		{"!(year = 2001) || title = other", false},
+		{"year == 2001 && not not title = movie", true},
+		{"YEAR = 2001 AND Title = Movie", true},
+	}
+
+	for _, test := range tests {
+		f, err := ParseFilter(test.expression)
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.expression, err)
+			continue
+		}
+
+		if actual := f.Match(movie); actual != test.expected {
+			t.Errorf("%s: expected %t, got %t", test.expression, test.expected, actual)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{"", "filter \"\" is empty"},
+		{"title = 'movie", "unterminated string in filter \"title = 'movie\""},
+		{"file ~ movie", "invalid filter \"file ~ movie\": unknown field \"file\""},
+		{"title", "invalid filter \"title\": expected an operator after \"title\""},
+		{"title =", "invalid filter \"title =\": expected a value after \"title ==\""},
+		{"title => movie", "invalid filter \"title => movie\": unknown operator \"=>\""},
+		{"title > movie", "invalid filter \"title > movie\": operator \">\" is not supported for \"title\""},
+		{"audio_languages < eng", "invalid filter \"audio_languages < eng\": operator \"<\" is not supported for \"audio_languages\""},
+		{"year ~ 2001", "invalid filter \"year ~ 2001\": operator \"~\" is not supported for \"year\""},
+		{"size > big", "invalid filter \"size > big\": invalid value \"big\" for \"size\": strconv.ParseFloat: parsing \"\": invalid syntax"},
+		{"quality > hd", "invalid filter \"quality > hd\": invalid value \"hd\" for \"quality\": expected a resolution such as 720p, 1080p or 4k"},
+		{"(year = 2001", "invalid filter \"(year = 2001\": missing \")\""},
+		{"year = 2001 year = 2002", "invalid filter \"year = 2001 year = 2002\": unexpected \"year\""},
+		{"year = 2001 and", "invalid filter \"year = 2001 and\": expected a field name"},
+		{"and year = 2001", "invalid filter \"and year = 2001\": expected a field name, got \"and\""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseFilter(test.expression)
+
+		if err == nil {
+			t.Errorf("%s: expected an error", test.expression)
+			continue
+		}
+
+		if err.Error() != test.expected {
+			t.Errorf("%s: expected error \"%s\", got \"%s\"", test.expression, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package plex
+
+import (
+	"fmt"
+	"github.com/dustin/go-humanize"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type statisticsGrouping struct {
+	title string
+	value func(m *Media) string
+}
+
+type statisticsGroup struct {
+	Count          int                         `json:"count"`
+	Metrics        map[string]statisticsMetric `json:"metrics"`
+	Percentage     float64                     `json:"percentage"`
+	Size           uint64                      `json:"size"`
+	SizePercentage float64                     `json:"size_percentage"`
+	Value          string                      `json:"value"`
+}
+
+var statisticsGroupings = map[string]statisticsGrouping{
+	"audio_codec": {title: "Audio Codec", value: func(m *Media) string { return m.AudioCodec }},
+	"decade": {title: "Decade", value: func(m *Media) string {
+		if m.Year == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("%ds", m.Year/10*10)
+	}},
+	"quality":     {title: "Quality", value: func(m *Media) string { return m.Quality }},
+	"show":        {title: "Show", value: func(m *Media) string { return m.Show }},
+	"video_codec": {title: "Video Codec", value: func(m *Media) string { return m.VideoCodec }},
+	"year": {title: "Year", value: func(m *Media) string {
+		if m.Year == 0 {
+			return ""
+		}
+
+		return strconv.Itoa(m.Year)
+	}},
+}
+
+func (s *Statistics) SetGroupBy(keys []string) error {
+	s.groupBy = make([]string, 0, len(keys))
+	s.groups = nil
+
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+
+		if k == "" {
+			continue
+		}
+
+		if _, ok := statisticsGroupings[k]; !ok {
+			names := make([]string, 0, len(statisticsGroupings))
+
+			for name := range statisticsGroupings {
+				names = append(names, name)
+			}
+
+			sort.Strings(names)
+
+			return fmt.Errorf("\"%s\" is not a valid grouping, expected one of %s", k, strings.Join(names, ", "))
+		}
+
+		s.groupBy = append(s.groupBy, k)
+	}
+
+	for _, l := range s.libraries {
+		if err := l.SetGroupBy(keys); err != nil {
+			return err
+		}
+	}
+
+	if len(s.groupBy) == 0 {
+		return nil
+	}
+
+	members := make(map[string][]*Media, 0)
+
+	for _, m := range s.probe.media {
+		values := make([]string, len(s.groupBy))
+
+		for k, g := range s.groupBy {
+			values[k] = statisticsGroupings[g].value(m)
+
+			if values[k] == "" {
+				values[k] = "unknown"
+			}
+		}
+
+		value := strings.Join(values, ", ")
+		members[value] = append(members[value], m)
+	}
+
+	var size uint64
+
+	for _, m := range s.probe.media {
+		size += m.Size
+	}
+
+	s.groups = make([]statisticsGroup, 0, len(members))
+
+	for value, media := range members {
+		g := statisticsGroup{
+			Count:      len(media),
+			Metrics:    make(map[string]statisticsMetric, 0),
+			Percentage: float64(len(media)) / float64(s.total) * 100,
+			Value:      value,
+		}
+
+		for _, m := range media {
+			g.Size += m.Size
+		}
+
+		if size > 0 {
+			g.SizePercentage = float64(g.Size) / float64(size) * 100
+		}
+
+		for _, k := range s.metricKeys() {
+			values, missing := knownValues(k, media)
+			g.Metrics[k] = newStatisticsMetric(k, values, missing, s.percentiles)
+		}
+
+		s.groups = append(s.groups, g)
+	}
+
+	sort.Slice(s.groups, func(i, j int) bool {
+		if s.groups[i].Count != s.groups[j].Count {
+			return s.groups[i].Count > s.groups[j].Count
+		}
+
+		return s.groups[i].Value < s.groups[j].Value
+	})
+
+	return nil
+}
+
+func (s *Statistics) groupTitle() string {
+	titles := make([]string, len(s.groupBy))
+
+	for k, g := range s.groupBy {
+		titles[k] = statisticsGroupings[g].title
+	}
+
+	return strings.Join(titles, " / ")
+}
+
+func (s *Statistics) groupRows() [][]string {
+	rows := make([][]string, len(s.groups))
+
+	for k, g := range s.groups {
+		rows[k] = []string{
+			g.Value,
+			strconv.Itoa(g.Count),
+			fmt.Sprintf("%.2f%%", g.Percentage),
+			humanize.Bytes(g.Size),
+			fmt.Sprintf("%.2f%%", g.SizePercentage),
+		}
+	}
+
+	return rows
+}
+
+type statisticsGroupTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+func (s *Statistics) groupTables() []statisticsGroupTable {
+	if len(s.groups) == 0 {
+		return nil
+	}
+
+	title := s.groupTitle()
+	tables := []statisticsGroupTable{
+		{
+			Header: []string{title, "Count", "Percentage", "Size", "Share of Size"},
+			Rows:   s.groupRows(),
+		},
+	}
+
+	for _, k := range s.metricKeys() {
+		tables = append(tables, statisticsGroupTable{
+			Header: s.metricHeader(fmt.Sprintf("%s by %s", strings.Title(k), title)),
+			Rows:   s.groupMetricRows(k),
+		})
+	}
+
+	return tables
+}
+
+func (s *Statistics) groupMetricRows(key string) [][]string {
+	rows := make([][]string, len(s.groups))
+
+	for k, g := range s.groups {
+		rows[k] = s.metricRow(g.Value, key, g.Metrics[key])
+	}
+
+	return rows
+}
@@ -0,0 +1,154 @@
+package plex
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const histogramBuckets = 10
+
+type statisticsBucket struct {
+	Count      int     `json:"count"`
+	Label      string  `json:"label"`
+	Maximum    float64 `json:"maximum"`
+	Minimum    float64 `json:"minimum"`
+	Percentage float64 `json:"percentage"`
+	Width      float64 `json:"-"`
+}
+
+type statisticsHistogram struct {
+	Buckets []statisticsBucket `json:"buckets"`
+	Key     string             `json:"-"`
+	Title   string             `json:"-"`
+}
+
+func (s *Statistics) histogramKeys() []string {
+	keys := make([]string, 0)
+
+	for _, k := range []string{"size", "bitrate", "duration", "year"} {
+		if containsString(s.metricKeys(), k) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+func (s *Statistics) histogramTables() []statisticsHistogram {
+	if !s.histograms {
+		return nil
+	}
+
+	histograms := make([]statisticsHistogram, 0)
+
+	for _, k := range s.histogramKeys() {
+		title := strings.Title(k)
+
+		if k == "year" {
+			title = "Decade"
+		}
+
+		buckets := histogram(k, s.values[k])
+
+		if len(buckets) == 0 {
+			continue
+		}
+
+		histograms = append(histograms, statisticsHistogram{
+			Buckets: buckets,
+			Key:     k,
+			Title:   title,
+		})
+	}
+
+	return histograms
+}
+
+// histogram splits values into equally wide buckets between their minimum
+// and maximum, except for years which are bucketed by decade.
+func histogram(key string, values []float64) []statisticsBucket {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := append([]float64{}, values...)
+
+	sort.Float64s(sorted)
+
+	minimum := sorted[0]
+	maximum := sorted[len(sorted)-1]
+	buckets := make([]statisticsBucket, 0)
+
+	var index func(v float64) int
+
+	if key == "year" {
+		start := math.Floor(minimum/10) * 10
+
+		for d := start; d <= maximum; d += 10 {
+			buckets = append(buckets, statisticsBucket{
+				Label:   fmt.Sprintf("%ds", int(d)),
+				Maximum: d + 9,
+				Minimum: d,
+			})
+		}
+
+		index = func(v float64) int {
+			return int((math.Floor(v/10)*10 - start) / 10)
+		}
+	} else {
+		n := histogramBuckets
+
+		if minimum == maximum {
+			n = 1
+		}
+
+		width := (maximum - minimum) / float64(n)
+
+		for i := 0; i < n; i++ {
+			lower := minimum + float64(i)*width
+			upper := minimum + float64(i+1)*width
+
+			if i == n-1 {
+				upper = maximum
+			}
+
+			buckets = append(buckets, statisticsBucket{
+				Label:   fmt.Sprintf("%s - %s", formatMetric(key, lower), formatMetric(key, upper)),
+				Maximum: upper,
+				Minimum: lower,
+			})
+		}
+
+		index = func(v float64) int {
+			if width == 0 {
+				return 0
+			}
+
+			return int(math.Min((v-minimum)/width, float64(n-1)))
+		}
+	}
+
+	largest := 0
+
+	for _, v := range sorted {
+		b := &buckets[index(v)]
+		b.Count++
+
+		if b.Count > largest {
+			largest = b.Count
+		}
+	}
+
+	for k := range buckets {
+		buckets[k].Percentage = float64(buckets[k].Count) / float64(len(sorted)) * 100
+		buckets[k].Width = float64(buckets[k].Count) / float64(largest) * 100
+	}
+
+	return buckets
+}
+
+func (b statisticsBucket) bar(width int) string {
+	return strings.Repeat("#", int(math.Round(b.Width/100*float64(width))))
+}
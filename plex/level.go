@@ -0,0 +1,210 @@
+package plex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Aggregate returns a copy of the probe where episodes are rolled up into one
+// row per show or season. Media that are not episodes are left as they are,
+// so that aggregating a combined probe keeps its movies and music intact.
+func (p *Probe) Aggregate(level string) (*Probe, error) {
+	level = strings.ToLower(strings.TrimSpace(level))
+
+	switch level {
+	case "", "episode":
+		return p, nil
+	case "season", "show":
+	default:
+		return nil, fmt.Errorf("\"%s\" is not a valid level, expected one of episode, season or show", level)
+	}
+
+	aggregated := *p
+	aggregated.level = level
+	aggregated.media = aggregateMedia(p.media, level)
+
+	if len(p.libraries) > 0 {
+		aggregated.libraries = make([]*Probe, len(p.libraries))
+
+		for k, l := range p.libraries {
+			library, err := l.Aggregate(level)
+
+			if err != nil {
+				return nil, err
+			}
+
+			aggregated.libraries[k] = library
+		}
+	}
+
+	return &aggregated, nil
+}
+
+func (p *Probe) Level() string {
+	if p.level == "" {
+		return "episode"
+	}
+
+	return p.level
+}
+
+func aggregateMedia(media []*Media, level string) []*Media {
+	aggregated := make([]*Media, 0)
+	episodes := make(map[string][]*Media, 0)
+	positions := make(map[string]int, 0)
+
+	for _, m := range media {
+		if m.Type != "episode" {
+			aggregated = append(aggregated, m)
+
+			continue
+		}
+
+		key := aggregateKey(m, level)
+
+		if _, ok := positions[key]; !ok {
+			positions[key] = len(aggregated)
+			aggregated = append(aggregated, nil)
+		}
+
+		episodes[key] = append(episodes[key], m)
+	}
+
+	for key, k := range positions {
+		aggregated[k] = newAggregateMedia(level, episodes[key])
+	}
+
+	return aggregated
+}
+
+// aggregateKey groups episodes by the rating key of their show or season, so
+// that different shows sharing a title stay apart. Titles are only used when
+// the rating keys are unknown.
+func aggregateKey(m *Media, level string) string {
+	if level == "season" {
+		if m.ParentKey != "" {
+			return m.ParentKey
+		}
+
+		return fmt.Sprintf("%s\x00%s\x00%d", m.Library, m.Show, m.Season)
+	}
+
+	if m.GrandparentKey != "" {
+		return m.GrandparentKey
+	}
+
+	return fmt.Sprintf("%s\x00%s", m.Library, m.Show)
+}
+
+func newAggregateMedia(level string, episodes []*Media) *Media {
+	first := episodes[0]
+	audioCodecs := make(map[string]int, 0)
+	frameRates := make(map[string]int, 0)
+	qualities := make(map[string]int, 0)
+	videoCodecs := make(map[string]int, 0)
+
+	a := &Media{
+		GrandparentKey: first.GrandparentKey,
+		Library:        first.Library,
+		Show:           first.Show,
+		Title:          first.Show,
+		Type:           level,
+	}
+
+	if level == "season" {
+		a.ParentKey = first.ParentKey
+		a.Season = first.Season
+		a.Title = fmt.Sprintf("%s (S%02d)", first.Show, first.Season)
+	}
+
+	var bitrate uint64
+	var bitrates uint64
+
+	// An episode with several versions is one Media per version, so the
+	// episode count and runtime are taken once per episode rating key,
+	// while the size covers every version on disk.
+	durations := make(map[string]time.Duration, 0)
+
+	for _, m := range episodes {
+		key := m.RatingKey
+
+		if key == "" {
+			key = m.Key()
+		}
+
+		if m.Duration > durations[key] {
+			durations[key] = m.Duration
+		}
+
+		a.Size += m.Size
+
+		if m.Bitrate > 0 {
+			bitrate += m.Bitrate
+			bitrates++
+		}
+
+		if m.Year > 0 && (a.Year == 0 || m.Year < a.Year) {
+			a.Year = m.Year
+		}
+
+		audioCodecs[m.AudioCodec]++
+		frameRates[m.FrameRate]++
+		qualities[m.Quality]++
+		videoCodecs[m.VideoCodec]++
+	}
+
+	for _, d := range durations {
+		a.Duration += d
+	}
+
+	a.Episodes = len(durations)
+
+	if bitrates > 0 {
+		a.Bitrate = bitrate / bitrates
+	}
+
+	a.AudioCodec = dominantValue(audioCodecs)
+	a.FrameRate = dominantValue(frameRates)
+	a.Quality = dominantValue(qualities)
+	a.VideoCodec = dominantValue(videoCodecs)
+
+	for q := range qualities {
+		if q != "" {
+			a.Qualities = append(a.Qualities, q)
+		}
+	}
+
+	sort.Slice(a.Qualities, func(i, j int) bool {
+		if qualityRank(a.Qualities[i]) != qualityRank(a.Qualities[j]) {
+			return qualityRank(a.Qualities[i]) > qualityRank(a.Qualities[j])
+		}
+
+		return a.Qualities[i] < a.Qualities[j]
+	})
+
+	return a
+}
+
+// dominantValue returns the most common non-empty value, preferring the
+// lowest value in sort order when several are equally common.
+func dominantValue(counts map[string]int) string {
+	dominant := ""
+
+	for v, c := range counts {
+		if v == "" {
+			continue
+		}
+
+		if dominant == "" || c > counts[dominant] || (c == counts[dominant] && v < dominant) {
+			dominant = v
+		}
+	}
+
+	return dominant
+}
+
+func (m *Media) MixedQuality() bool {
+	return len(m.Qualities) > 1
+}
@@ -0,0 +1,139 @@
+package plex
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const plexTVURL = "https://plex.tv"
+
+// pinLifetime bounds the wait for a code that has no expiry time.
+const pinLifetime = 15 * time.Minute
+
+type Pin struct {
+	AuthToken string    `json:"authToken"`
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	ID        int       `json:"id"`
+}
+
+type PinService interface {
+	CheckPin(ctx context.Context, id int) (*Pin, error)
+	RequestPin(ctx context.Context) (*Pin, error)
+}
+
+type PlexTV struct {
+	clientIdentifier string
+	httpClient       *http.Client
+	url              string
+}
+
+func NewPlexTV(url string) (*PlexTV, error) {
+	if url == "" {
+		url = plexTVURL
+	}
+
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	return &PlexTV{
+		clientIdentifier: hex.EncodeToString(id),
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+		url:              url,
+	}, nil
+}
+
+func (t *PlexTV) CheckPin(ctx context.Context, id int) (*Pin, error) {
+	return t.pin(ctx, "GET", fmt.Sprintf("/api/v2/pins/%d", id), http.StatusOK)
+}
+
+func (t *PlexTV) RequestPin(ctx context.Context) (*Pin, error) {
+	return t.pin(ctx, "POST", "/api/v2/pins", http.StatusCreated)
+}
+
+func (t *PlexTV) pin(ctx context.Context, method string, path string, status int) (*Pin, error) {
+	req, err := http.NewRequest(method, t.url+path, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Client-Identifier", t.clientIdentifier)
+	req.Header.Set("X-Plex-Product", "Plex Tools")
+
+	resp, err := t.httpClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != status {
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	pin := &Pin{}
+
+	if err := json.NewDecoder(resp.Body).Decode(pin); err != nil {
+		return nil, err
+	}
+
+	return pin, nil
+}
+
+// Login requests a code, shows it and polls until it has been linked to an
+// account, the code expires or the context is cancelled.
+func Login(ctx context.Context, s PinService, interval time.Duration, show func(code string)) (string, error) {
+	pin, err := s.RequestPin(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	show(pin.Code)
+
+	expiresAt := pin.ExpiresAt
+
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(pinLifetime)
+	}
+
+	for {
+		if time.Now().After(expiresAt) {
+			return "", errors.New("the code expired before it was linked, please try again")
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+
+		checked, err := s.CheckPin(ctx, pin.ID)
+
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if checked.AuthToken != "" {
+			return checked.AuthToken, nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package plex
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pinServer answers pin requests like plex.tv. The pin is linked after the
+// given number of checks, or never when claimAfter is zero.
+func pinServer(t *testing.T, expiresAt string, claimAfter int, checkStatus int) *httptest.Server {
+	checks := 0
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Client-Identifier") == "" {
+			t.Error("expected a client identifier")
+		}
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/api/v2/pins":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = fmt.Fprintf(w, `{"id": 1, "code": "ABCD", "expiresAt": %s}`, expiresAt)
+		case r.Method == "GET" && r.URL.Path == "/api/v2/pins/1":
+			if checkStatus != http.StatusOK {
+				w.WriteHeader(checkStatus)
+
+				return
+			}
+
+			checks++
+			token := ""
+
+			if claimAfter > 0 && checks >= claimAfter {
+				token = "token"
+			}
+
+			_, _ = fmt.Fprintf(w, `{"id": 1, "code": "ABCD", "authToken": "%s"}`, token)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestLoginClaimed(t *testing.T) {
+	s := pinServer(t, `"`+time.Now().Add(time.Minute).Format(time.RFC3339)+`"`, 2, http.StatusOK)
+
+	defer s.Close()
+
+	tv, err := NewPlexTV(s.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shown := ""
+	token, err := Login(context.Background(), tv, time.Millisecond, func(code string) {
+		shown = code
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shown != "ABCD" || token != "token" {
+		t.Errorf("expected code \"ABCD\" and token \"token\", got \"%s\" and \"%s\"", shown, token)
+	}
+}
+
+func TestLoginExpired(t *testing.T) {
+	s := pinServer(t, `"`+time.Now().Add(-time.Minute).Format(time.RFC3339)+`"`, 0, http.StatusOK)
+
+	defer s.Close()
+
+	tv, err := NewPlexTV(s.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Login(context.Background(), tv, time.Millisecond, func(string) {}); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected the code to expire, got %v", err)
+	}
+}
+
+func TestLoginFailsOnError(t *testing.T) {
+	s := pinServer(t, "null", 0, http.StatusInternalServerError)
+
+	defer s.Close()
+
+	tv, err := NewPlexTV(s.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Login(context.Background(), tv, time.Millisecond, func(string) {}); err == nil {
+		t.Error("expected an error when plex.tv fails")
+	}
+}
+
+func TestLoginCancelled(t *testing.T) {
+	s := pinServer(t, "null", 0, http.StatusOK)
+
+	defer s.Close()
+
+	tv, err := NewPlexTV(s.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+	defer cancel()
+
+	if _, err := Login(ctx, tv, time.Millisecond, func(string) {}); err != context.DeadlineExceeded {
+		t.Errorf("expected the login to be cancelled, got %v", err)
+	}
+}
@@ -11,17 +11,45 @@ import (
 )
 
 type Media struct {
-	AudioChannels int
-	AudioCodec    string
-	Bitrate       uint64
-	Duration      time.Duration
-	FrameRate     string
-	Quality       string
-	Rating        float64
-	Size          uint64
-	Title         string
-	VideoCodec    string
-	Year          int
+	Album           string
+	Artist          string
+	AudioChannels   int
+	AudioCodec      string
+	AudioStreams    []*Stream
+	Bitrate         uint64
+	Duration        time.Duration
+	Episode         int
+	Episodes        int
+	FrameRate       string
+	GUID            string
+	GrandparentKey  string
+	Height          int
+	ID              int
+	Library         string
+	ParentKey       string
+	Parts           []*Part
+	Qualities       []string
+	Quality         string
+	Rating          float64
+	RatingKey       string
+	Season          int
+	Show            string
+	Size            uint64
+	SubtitleStreams []*Stream
+	Title           string
+	Track           int
+	Type            string
+	VideoCodec      string
+	VideoStreams    []*Stream
+	Width           int
+	Year            int
+}
+
+type Part struct {
+	Container string
+	Duration  time.Duration
+	File      string
+	Size      uint64
 }
 
 var specialCharacters = regexp.MustCompile(`(\s|\.|,|_|-|=|'|\|)+`)
@@ -40,36 +68,114 @@ func NewMediaSlice(v plex.Metadata) []*Media {
 			quality += "p"
 		}
 
-		for _, p := range m.Part {
-			title := v.Title
+		album := ""
+		artist := ""
+		episode := 0
+		season := 0
+		show := ""
+		title := v.Title
+		track := 0
+
+		switch v.Type {
+		case "episode":
+			episode = int(v.Index)
+			season = int(v.ParentIndex)
+			show = v.GrandparentTitle
+			title = fmt.Sprintf("%s (S%02dE%02d): %s", v.GrandparentTitle, v.ParentIndex, v.Index, v.Title)
+		case "photo":
+			album = v.ParentTitle
+		case "track":
+			album = v.ParentTitle
+			artist = v.GrandparentTitle
+			track = int(v.Index)
+		}
 
-			if v.Type == "episode" {
-				title = fmt.Sprintf("%s (S%02dE%02d): %s", v.GrandparentTitle, v.ParentIndex, v.Index, v.Title)
-			}
+		media[k] = &Media{
+			Album:          album,
+			Artist:         artist,
+			AudioChannels:  m.AudioChannels,
+			AudioCodec:     m.AudioCodec,
+			Bitrate:        uint64(m.Bitrate) * humanize.KByte,
+			Episode:        episode,
+			FrameRate:      m.VideoFrameRate,
+			GUID:           v.GUID,
+			GrandparentKey: v.GrandparentRatingKey,
+			Height:         m.Height,
+			ID:             m.ID,
+			ParentKey:      v.ParentRatingKey,
+			Parts:          make([]*Part, len(m.Part)),
+			Quality:        quality,
+			Rating:         v.Rating,
+			RatingKey:      v.RatingKey,
+			Season:         season,
+			Show:           show,
+			Title:          title,
+			Track:          track,
+			Type:           v.Type,
+			VideoCodec:     m.VideoCodec,
+			Width:          m.Width,
+			Year:           v.Year,
+		}
 
-			media[k] = &Media{
-				AudioChannels: m.AudioChannels,
-				AudioCodec:    m.AudioCodec,
-				Bitrate:       uint64(m.Bitrate) * humanize.KByte,
-				Duration:      time.Duration(p.Duration) * time.Millisecond,
-				FrameRate:     m.VideoFrameRate,
-				Quality:       quality,
-				Rating:        v.Rating,
-				Size:          uint64(p.Size),
-				Title:         title,
-				VideoCodec:    m.VideoCodec,
-				Year:          v.Year,
+		for i, p := range m.Part {
+			part := &Part{
+				Container: p.Container,
+				Duration:  time.Duration(p.Duration) * time.Millisecond,
+				File:      p.File,
+				Size:      uint64(p.Size),
 			}
+
+			media[k].Duration += part.Duration
+			media[k].Parts[i] = part
+			media[k].Size += part.Size
+		}
+
+		if media[k].Duration == 0 {
+			media[k].Duration = time.Duration(m.Duration) * time.Millisecond
 		}
 	}
 
 	return media
 }
 
+func (m *Media) Container() string {
+	if len(m.Parts) == 0 {
+		return ""
+	}
+
+	return m.Parts[0].Container
+}
+
+func (m *Media) Dimensions() string {
+	if m.Width == 0 || m.Height == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dx%d", m.Width, m.Height)
+}
+
 func (m *Media) DurationInNanoseconds() int64 {
 	return m.Duration.Nanoseconds()
 }
 
+func (m *Media) File() string {
+	if len(m.Parts) == 0 {
+		return ""
+	}
+
+	return m.Parts[0].File
+}
+
+func (m *Media) Files() []string {
+	files := make([]string, len(m.Parts))
+
+	for k, p := range m.Parts {
+		files[k] = p.File
+	}
+
+	return files
+}
+
 func (m *Media) HumanizeBitRate() string {
 	return humanize.Bytes(m.Bitrate)
 }
@@ -82,6 +188,10 @@ func (m *Media) HumanizeSize() string {
 	return humanize.Bytes(m.Size)
 }
 
+func (m *Media) Key() string {
+	return fmt.Sprintf("%s/%d", m.RatingKey, m.ID)
+}
+
 func (m *Media) SortTitle() string {
 	title := specialCharacters.ReplaceAllString(m.Title, " ")
 	title = nonWordCharacters.ReplaceAllString(title, "")
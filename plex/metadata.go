@@ -0,0 +1,292 @@
+package plex
+
+import (
+	"context"
+	"fmt"
+	"github.com/jrudio/go-plex-client"
+	"github.com/olekukonko/tablewriter"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var MetadataFields = []string{"title", "summary", "genres", "rating", "year", "poster", "background"}
+
+type Metadata struct {
+	Background string
+	Genres     []string
+	Poster     string
+	Rating     float64
+	Summary    string
+	Title      string
+	Year       int
+}
+
+type MetadataQuery struct {
+	Files []string
+	GUID  string
+	Title string
+	Type  string
+	Year  int
+}
+
+type MetadataProvider interface {
+	Lookup(q *MetadataQuery) (*Metadata, error)
+	Name() string
+}
+
+type MetadataItem struct {
+	Current   *Metadata
+	Files     []string
+	GUID      string
+	RatingKey string
+	Title     string
+	Type      string
+}
+
+type MetadataUpdate struct {
+	Changes  []Change
+	Item     *MetadataItem
+	Metadata *Metadata
+}
+
+func (p *Plex) MetadataItems(ctx context.Context, libraryKey string) ([]*MetadataItem, error) {
+	metadata, err := p.walkLibrary(ctx, libraryKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*MetadataItem, 0)
+
+	for _, m := range metadata {
+		if m.Type == "episode" || m.Type == "movie" {
+			items = append(items, newMetadataItem(m))
+		}
+	}
+
+	return items, nil
+}
+
+// LoadMetadata replaces the item's current metadata with the full record of
+// the item. Library listings truncate tag lists such as genres, so updates
+// must be computed against this record rather than the listing.
+func (p *Plex) LoadMetadata(ctx context.Context, item *MetadataItem) error {
+	var mc libraryContainer
+
+	if err := p.request(ctx, "GET", fmt.Sprintf("/library/metadata/%s", item.RatingKey), nil, &mc); err != nil {
+		return err
+	}
+
+	if len(mc.MediaContainer.Metadata) == 0 {
+		return fmt.Errorf("no metadata found for \"%s\"", item.Title)
+	}
+
+	item.Current = newMetadataItem(mc.MediaContainer.Metadata[0]).Current
+
+	return nil
+}
+
+func newMetadataItem(m libraryItem) *MetadataItem {
+	title := m.Title
+
+	if m.Type == "episode" {
+		title = fmt.Sprintf("%s (S%02dE%02d): %s", m.GrandparentTitle, m.ParentIndex, m.Index, m.Title)
+	}
+
+	i := &MetadataItem{
+		Current: &Metadata{
+			Background: m.Art,
+			Genres:     make([]string, len(m.Genre)),
+			Poster:     m.Thumb,
+			Rating:     m.Rating,
+			Summary:    m.Summary,
+			Title:      m.Title,
+			Year:       m.Year,
+		},
+		Files:     make([]string, 0),
+		GUID:      m.GUID,
+		RatingKey: m.RatingKey,
+		Title:     title,
+		Type:      m.Type,
+	}
+
+	for k, g := range m.Genre {
+		i.Current.Genres[k] = g.Tag
+	}
+
+	for _, media := range m.Media {
+		for _, part := range media.Part {
+			i.Files = append(i.Files, part.File)
+		}
+	}
+
+	return i
+}
+
+func (i *MetadataItem) Query() *MetadataQuery {
+	return &MetadataQuery{
+		Files: i.Files,
+		GUID:  i.GUID,
+		Title: i.Current.Title,
+		Type:  i.Type,
+		Year:  i.Current.Year,
+	}
+}
+
+func NewMetadataUpdate(item *MetadataItem, metadata *Metadata, fields []string) *MetadataUpdate {
+	u := &MetadataUpdate{
+		Changes:  make([]Change, 0),
+		Item:     item,
+		Metadata: metadata,
+	}
+
+	current := item.Current
+
+	for _, f := range fields {
+		switch f {
+		case "background":
+			if metadata.Background != "" && metadata.Background != current.Background {
+				u.Changes = append(u.Changes, Change{Field: f, Old: current.Background, New: metadata.Background})
+			}
+		case "genres":
+			if len(metadata.Genres) > 0 && !sameStrings(metadata.Genres, current.Genres) {
+				u.Changes = append(u.Changes, Change{Field: f, Old: strings.Join(current.Genres, ", "), New: strings.Join(metadata.Genres, ", ")})
+			}
+		case "poster":
+			if metadata.Poster != "" && metadata.Poster != current.Poster {
+				u.Changes = append(u.Changes, Change{Field: f, Old: current.Poster, New: metadata.Poster})
+			}
+		case "rating":
+			if metadata.Rating != 0 && metadata.Rating != current.Rating {
+				u.Changes = append(u.Changes, Change{Field: f, Old: fmt.Sprintf("%.1f", current.Rating), New: fmt.Sprintf("%.1f", metadata.Rating)})
+			}
+		case "summary":
+			if metadata.Summary != "" && metadata.Summary != current.Summary {
+				u.Changes = append(u.Changes, Change{Field: f, Old: current.Summary, New: metadata.Summary})
+			}
+		case "title":
+			if metadata.Title != "" && metadata.Title != current.Title {
+				u.Changes = append(u.Changes, Change{Field: f, Old: current.Title, New: metadata.Title})
+			}
+		case "year":
+			if metadata.Year != 0 && metadata.Year != current.Year {
+				u.Changes = append(u.Changes, Change{Field: f, Old: strconv.Itoa(current.Year), New: strconv.Itoa(metadata.Year)})
+			}
+		}
+	}
+
+	return u
+}
+
+func sameStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Plex) ApplyMetadata(ctx context.Context, libraryKey string, u *MetadataUpdate) error {
+	query := url.Values{}
+
+	query.Set("type", plex.GetMediaTypeID(u.Item.Type))
+	query.Set("id", u.Item.RatingKey)
+
+	artwork := make(map[string]string, 0)
+
+	for _, c := range u.Changes {
+		switch c.Field {
+		case "background":
+			artwork["arts"] = u.Metadata.Background
+		case "genres":
+			for k, g := range u.Metadata.Genres {
+				query.Set(fmt.Sprintf("genre[%d].tag.tag", k), g)
+			}
+
+			removed := make([]string, 0)
+
+			for _, g := range u.Item.Current.Genres {
+				if !containsString(u.Metadata.Genres, g) {
+					removed = append(removed, g)
+				}
+			}
+
+			if len(removed) > 0 {
+				query.Set("genre[].tag.tag-", strings.Join(removed, ","))
+			}
+
+			query.Set("genre.locked", "1")
+		case "poster":
+			artwork["posters"] = u.Metadata.Poster
+		case "rating":
+			query.Set("rating.value", strconv.FormatFloat(u.Metadata.Rating, 'f', 1, 64))
+			query.Set("rating.locked", "1")
+		case "summary":
+			query.Set("summary.value", u.Metadata.Summary)
+			query.Set("summary.locked", "1")
+		case "title":
+			query.Set("title.value", u.Metadata.Title)
+			query.Set("title.locked", "1")
+		case "year":
+			query.Set("year.value", strconv.Itoa(u.Metadata.Year))
+			query.Set("year.locked", "1")
+		}
+	}
+
+	if len(query) > 2 {
+		if err := p.request(ctx, "PUT", fmt.Sprintf("/library/sections/%s/all", libraryKey), query, nil); err != nil {
+			return err
+		}
+	}
+
+	for endpoint, location := range artwork {
+		q := url.Values{}
+
+		q.Set("url", location)
+
+		if err := p.request(ctx, "POST", fmt.Sprintf("/library/metadata/%s/%s", u.Item.RatingKey, endpoint), q, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func MetadataUpdatesAscii(w io.Writer, updates []*MetadataUpdate) {
+	t := tablewriter.NewWriter(w)
+
+	t.SetHeader([]string{"Title", "Field", "Current", "New"})
+
+	for _, u := range updates {
+		for _, c := range u.Changes {
+			t.Append([]string{u.Item.Title, c.Field, c.Old, c.New})
+		}
+	}
+
+	t.Render()
+}
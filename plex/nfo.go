@@ -0,0 +1,139 @@
+package plex
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type NfoProvider struct{}
+
+type nfo struct {
+	Aired     string   `xml:"aired"`
+	Fanart    []string `xml:"fanart>thumb"`
+	Genre     []string `xml:"genre"`
+	Plot      string   `xml:"plot"`
+	Premiered string   `xml:"premiered"`
+	Rating    string   `xml:"rating"`
+	Ratings   []struct {
+		Default bool    `xml:"default,attr"`
+		Value   float64 `xml:"value"`
+	} `xml:"ratings>rating"`
+	Thumb []struct {
+		Aspect string `xml:"aspect,attr"`
+		URL    string `xml:",chardata"`
+	} `xml:"thumb"`
+	Title string `xml:"title"`
+	Year  int    `xml:"year"`
+}
+
+func NewNfoProvider() *NfoProvider {
+	return &NfoProvider{}
+}
+
+func (n *NfoProvider) Name() string {
+	return "nfo"
+}
+
+func (n *NfoProvider) Lookup(q *MetadataQuery) (*Metadata, error) {
+	for _, path := range nfoPaths(q) {
+		f, err := os.Open(path)
+
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var v nfo
+
+		err = xml.NewDecoder(f).Decode(&v)
+		_ = f.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return v.metadata(), nil
+	}
+
+	return nil, nil
+}
+
+func nfoPaths(q *MetadataQuery) []string {
+	paths := make([]string, 0)
+
+	for _, file := range q.Files {
+		paths = append(paths, strings.TrimSuffix(file, filepath.Ext(file))+".nfo")
+	}
+
+	if q.Type == "movie" {
+		for _, file := range q.Files {
+			paths = append(paths, filepath.Join(filepath.Dir(file), "movie.nfo"))
+		}
+	}
+
+	return paths
+}
+
+func (v *nfo) metadata() *Metadata {
+	m := &Metadata{
+		Genres:  make([]string, 0),
+		Summary: strings.TrimSpace(v.Plot),
+		Title:   strings.TrimSpace(v.Title),
+		Year:    v.Year,
+	}
+
+	for _, g := range v.Genre {
+		for _, s := range strings.Split(g, "/") {
+			if s = strings.TrimSpace(s); s != "" {
+				m.Genres = append(m.Genres, s)
+			}
+		}
+	}
+
+	if r, err := strconv.ParseFloat(strings.TrimSpace(v.Rating), 64); err == nil {
+		m.Rating = r
+	}
+
+	for _, r := range v.Ratings {
+		if r.Default || m.Rating == 0 {
+			m.Rating = r.Value
+		}
+	}
+
+	if m.Year == 0 {
+		for _, date := range []string{v.Premiered, v.Aired} {
+			if y, err := strconv.Atoi(strings.SplitN(strings.TrimSpace(date), "-", 2)[0]); err == nil && y > 0 {
+				m.Year = y
+				break
+			}
+		}
+	}
+
+	for _, t := range v.Thumb {
+		if isURL(t.URL) && (t.Aspect == "" || t.Aspect == "poster") {
+			m.Poster = strings.TrimSpace(t.URL)
+			break
+		}
+	}
+
+	for _, f := range v.Fanart {
+		if isURL(f) {
+			m.Background = strings.TrimSpace(f)
+			break
+		}
+	}
+
+	return m
+}
+
+func isURL(s string) bool {
+	s = strings.TrimSpace(s)
+
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
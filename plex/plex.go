@@ -1,18 +1,38 @@
 package plex
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/jrudio/go-plex-client"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
 type Plex struct {
-	client *plex.Plex
-	server *Server
-	token  string
+	client      *plex.Plex
+	concurrency int
+	connection  string
+	preferLocal bool
+	progress    ProgressFunc
+	server      *Server
+	streams     bool
+	token       string
 }
 
-type Server = plex.PMSDevices
+type Server struct {
+	plex.PMSDevices
+	Connection []Connection `xml:"Connection"`
+}
+
+type Connection struct {
+	plex.Connection
+	IPv6  int `xml:"IPv6,attr"`
+	Relay int `xml:"relay,attr"`
+}
 
 func New(token string) (*Plex, error) {
 	if token == "" {
@@ -26,8 +46,10 @@ func New(token string) (*Plex, error) {
 	}
 
 	return &Plex{
-		client: c,
-		token:  token,
+		client:      c,
+		concurrency: 8,
+		preferLocal: true,
+		token:       token,
 	}, nil
 }
 
@@ -47,8 +69,55 @@ func (p *Plex) GetLibraryKeyByTitle(title string) (string, error) {
 	return "", fmt.Errorf("no library titled \"%s\" found", title)
 }
 
+func (p *Plex) GetServers() ([]Server, error) {
+	req, err := http.NewRequest("GET", plexTVURL+"/api/resources?includeHttps=1&includeRelay=1&includeIPv6=1", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Plex-Client-Identifier", p.client.ClientIdentifier)
+	req.Header.Set("X-Plex-Product", p.client.Headers.Product)
+	req.Header.Set("X-Plex-Version", p.client.Headers.Version)
+	req.Header.Set("X-Plex-Token", p.token)
+
+	resp, err := p.client.HTTPClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to list Plex servers: %s", resp.Status)
+	}
+
+	var resources struct {
+		Device []Server `xml:"Device"`
+	}
+
+	if err := xml.NewDecoder(resp.Body).Decode(&resources); err != nil {
+		return nil, err
+	}
+
+	servers := make([]Server, 0)
+
+	for _, d := range resources.Device {
+		if strings.Contains(d.Provides, "server") {
+			servers = append(servers, d)
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, errors.New("no Plex servers available for this access token")
+	}
+
+	return servers, nil
+}
+
 func (p *Plex) GetServerByName(name string) (*Server, error) {
-	servers, err := p.client.GetServers()
+	servers, err := p.GetServers()
 
 	if err != nil {
 		return nil, err
@@ -63,15 +132,157 @@ func (p *Plex) GetServerByName(name string) (*Server, error) {
 	return nil, fmt.Errorf("no server named \"%s\" found", name)
 }
 
-func (p *Plex) UseServer(server *Server) {
-	p.client.Token = server.AccessToken
+func (p *Plex) LibraryKeys() ([]string, error) {
+	libraries, err := p.client.GetLibraries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(libraries.MediaContainer.Directory))
+
+	for k, l := range libraries.MediaContainer.Directory {
+		keys[k] = l.Key
+	}
+
+	return keys, nil
+}
 
-	for _, c := range server.Connection {
-		if c.Local == 0 {
-			p.client.URL = c.URI
-			break
+func (p *Plex) ResolveLibraryKey(keyOrTitle string) (string, error) {
+	libraries, err := p.client.GetLibraries()
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, l := range libraries.MediaContainer.Directory {
+		if l.Key == keyOrTitle {
+			return l.Key, nil
+		}
+	}
+
+	for _, l := range libraries.MediaContainer.Directory {
+		if l.Title == keyOrTitle {
+			return l.Key, nil
 		}
 	}
 
+	return "", fmt.Errorf("no library with key or title \"%s\" found", keyOrTitle)
+}
+
+func (p *Plex) SetConcurrency(concurrency int) error {
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+
+	p.concurrency = concurrency
+
+	return nil
+}
+
+func (p *Plex) SetConnectionPreference(connection string, preferLocal bool) {
+	p.connection = connection
+	p.preferLocal = preferLocal
+}
+
+func (p *Plex) SetLoadStreams(enabled bool) {
+	p.streams = enabled
+}
+
+func (p *Plex) UseServer(server *Server) error {
+	candidates, err := p.connectionCandidates(server)
+
+	if err != nil {
+		return err
+	}
+
+	uri, err := p.firstReachable(server.AccessToken, candidates)
+
+	if err != nil {
+		return fmt.Errorf("unable to connect to \"%s\": %s", server.Name, err)
+	}
+
+	p.client.Token = server.AccessToken
+	p.client.URL = uri
 	p.server = server
+
+	return nil
+}
+
+func (p *Plex) UseURL(serverURL string) error {
+	u, err := url.Parse(serverURL)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("\"%s\" is not a valid server URL, expected e.g. \"http://%s\"", serverURL, strings.TrimPrefix(serverURL, "//"))
+	}
+
+	p.client.URL = strings.TrimRight(serverURL, "/")
+
+	var identity struct {
+		MediaContainer struct {
+			FriendlyName      string `json:"friendlyName"`
+			MachineIdentifier string `json:"machineIdentifier"`
+			Version           string `json:"version"`
+		} `json:"MediaContainer"`
+	}
+
+	if err := p.request(context.Background(), "GET", "/", nil, &identity); err != nil {
+		return fmt.Errorf("unable to connect to \"%s\": %s", serverURL, err)
+	}
+
+	p.server = &Server{
+		PMSDevices: plex.PMSDevices{
+			AccessToken:      p.token,
+			ClientIdentifier: identity.MediaContainer.MachineIdentifier,
+			Name:             identity.MediaContainer.FriendlyName,
+			Product:          "Plex Media Server",
+			ProductVersion:   identity.MediaContainer.Version,
+			Provides:         "server",
+		},
+		Connection: []Connection{
+			{Connection: plex.Connection{URI: p.client.URL}},
+		},
+	}
+
+	return nil
+}
+
+func (p *Plex) request(ctx context.Context, method string, path string, query url.Values, v interface{}) error {
+	u := p.client.URL + path
+
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, nil)
+
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Plex-Client-Identifier", p.client.ClientIdentifier)
+	req.Header.Set("X-Plex-Product", p.client.Headers.Product)
+	req.Header.Set("X-Plex-Version", p.client.Headers.Version)
+	req.Header.Set("X-Plex-Token", p.client.Token)
+
+	resp, err := p.client.HTTPClient.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
 }
@@ -0,0 +1,214 @@
+package plex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeServer serves canned JSON responses keyed by request path, and fails
+// requests without the expected token.
+func fakeServer(t *testing.T, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		body, ok := responses[r.URL.Path]
+
+		if !ok {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+}
+
+const identityResponse = `{"MediaContainer": {"friendlyName": "Test Server", "machineIdentifier": "abc", "version": "1.0"}}`
+
+func TestUseURL(t *testing.T) {
+	s := fakeServer(t, map[string]string{"/": identityResponse})
+
+	defer s.Close()
+
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL + "/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.server.Name != "Test Server" {
+		t.Errorf("expected server name \"Test Server\", got \"%s\"", p.server.Name)
+	}
+
+	if p.client.URL != s.URL {
+		t.Errorf("expected URL \"%s\", got \"%s\"", s.URL, p.client.URL)
+	}
+}
+
+func TestUseURLRequiresScheme(t *testing.T) {
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []string{"192.168.1.10:32400", "localhost", "ftp://localhost:32400"} {
+		if err := p.UseURL(u); err == nil {
+			t.Errorf("expected an error for \"%s\"", u)
+		}
+	}
+}
+
+func TestUseURLUnauthorized(t *testing.T) {
+	s := fakeServer(t, map[string]string{"/": identityResponse})
+
+	defer s.Close()
+
+	p, err := New("wrong")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL); err == nil {
+		t.Error("expected an error for an invalid token")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	s := fakeServer(t, map[string]string{
+		"/": identityResponse,
+		"/library/sections/1/all": `{"MediaContainer": {"librarySectionTitle": "Mixed", "Metadata": [
+			{"ratingKey": "1", "type": "movie", "title": "Movie", "year": 2001, "Media": [
+				{"id": 11, "videoResolution": "1080", "bitrate": 8000, "Part": [{"file": "/movie-1080p.mkv", "size": 2000, "duration": 60000, "container": "mkv"}]},
+				{"id": 12, "videoResolution": "sd", "bitrate": 1000, "Part": [{"file": "/movie-sd.avi", "size": 500, "duration": 60000, "container": "avi"}]}
+			]},
+			{"ratingKey": "2", "type": "show", "title": "Show"}
+		]}}`,
+		"/library/metadata/2/children": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "3", "type": "season", "title": "Season 1"}
+		]}}`,
+		"/library/metadata/3/children": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "4", "type": "episode", "title": "Pilot", "grandparentTitle": "Show", "grandparentRatingKey": "2", "parentRatingKey": "3", "parentIndex": 1, "index": 1, "Media": [
+				{"id": 41, "videoResolution": "720", "Part": [{"file": "/show/s01e01.mkv", "size": 300, "duration": 30000}]}
+			]}
+		]}}`,
+	})
+
+	defer s.Close()
+
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	probe, err := p.Probe(context.Background(), "1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	media := probe.Media()
+
+	if len(media) != 3 {
+		t.Fatalf("expected 3 media, got %d", len(media))
+	}
+
+	expected := []struct {
+		file    string
+		quality string
+		title   string
+	}{
+		{"/movie-1080p.mkv", "1080p", "Movie"},
+		{"/movie-sd.avi", "SD", "Movie"},
+		{"/show/s01e01.mkv", "720p", "Show (S01E01): Pilot"},
+	}
+
+	for k, e := range expected {
+		m := media[k]
+
+		if m.File() != e.file || m.Quality != e.quality || m.Title != e.title || m.Library != "Mixed" {
+			t.Errorf("unexpected media %d: %+v", k, m)
+		}
+	}
+
+	if media[2].GrandparentKey != "2" || media[2].ParentKey != "3" || media[2].Season != 1 || media[2].Episode != 1 {
+		t.Errorf("unexpected episode keys: %+v", media[2])
+	}
+}
+
+func TestProbeFailsOnServerError(t *testing.T) {
+	s := fakeServer(t, map[string]string{"/": identityResponse})
+
+	defer s.Close()
+
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	if _, err := p.Probe(context.Background(), "1"); err == nil {
+		t.Error("expected an error when the server fails")
+	}
+}
+
+func TestLoadMetadata(t *testing.T) {
+	s := fakeServer(t, map[string]string{
+		"/": identityResponse,
+		"/library/metadata/1": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "1", "type": "movie", "title": "Movie", "summary": "The full summary.", "Genre": [{"tag": "Action"}, {"tag": "Drama"}, {"tag": "Thriller"}]}
+		]}}`,
+	})
+
+	defer s.Close()
+
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &MetadataItem{
+		Current:   &Metadata{Genres: []string{"Action"}, Summary: "The full"},
+		RatingKey: "1",
+		Title:     "Movie",
+	}
+
+	if err := p.LoadMetadata(context.Background(), item); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(item.Current.Genres) != 3 || item.Current.Summary != "The full summary." {
+		t.Errorf("unexpected metadata: %+v", item.Current)
+	}
+}
@@ -1,12 +1,18 @@
 package plex
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"github.com/dustin/go-humanize"
 	"github.com/jrudio/go-plex-client"
 	"github.com/olekukonko/tablewriter"
 	"html/template"
 	"io"
-	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const probeTemplate = `<!doctype html>
@@ -14,162 +20,410 @@ const probeTemplate = `<!doctype html>
 	<head>
 		<meta charset="utf-8">
 		<meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
-		<link rel="stylesheet" href="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/css/bootstrap.min.css" integrity="sha384-ggOyR0iXCbMQv3Xipma34MD+dH/1fQ784/j6cY/iJTQUOhcWr7x9JvoRxT2MZw1T" crossorigin="anonymous">
+		{{stylesheet}}
 		<title>Probe: {{.Probe.Library}} @ {{.Probe.Server.Name}}</title>
 	</head>
 	<body>
-		<table id="probe" class="table table-striped table-sm">
+		<h1>{{.Probe.Library}} @ {{.Probe.Server.Name}}</h1>
+		<dl class="summary">
+			{{range .Summary}}<div>
+				<dt>{{.Label}}</dt>
+				<dd>{{.Value}}</dd>
+			</div>
+			{{end}}
+		</dl>
+		<input type="search" class="form-control" placeholder="Search" aria-label="Search" data-table="probe">
+		<table id="probe" class="table table-striped table-sm sortable">
 			<thead class="thead-dark">
 				<tr>
-					<th scope="col">Title</th>
-					<th scope="col">Year</th>
-					<th scope="col">Duration</th>
-					<th scope="col">Rating</th>
-					<th scope="col">Size</th>
-					<th scope="col">Quality</th>
-					<th scope="col">Bitrate</th>
-					<th scope="col">Video</th>
-					<th scope="col">Frame Rate</th>
-					<th scope="col">Audio</th>
-					<th scope="col">Channels</th>
+					{{range .Headers}}<th scope="col">{{.}}</th>
+					{{end}}
 				</tr>
 			</thead>
 			<tbody>
-				{{range .Probe.Media}}<tr>
-					<td data-sort="{{.SortTitle}}">{{.Title}}</td>
-					<td>{{.Year}}</td>
-					<td data-sort="{{.DurationInNanoseconds}}">{{.HumanizeDuration}}</td>
-					<td>{{.Rating}}</td>
-					<td data-sort="{{.Size}}">{{.HumanizeSize}}</td>
-					<td>{{.Quality}}</td>
-					<td data-sort="{{.Bitrate}}">{{.HumanizeBitRate}}</td>
-					<td>{{.VideoCodec}}</td>
-					<td>{{.FrameRate}}</td>
-					<td>{{.AudioCodec}}</td>
-					<td>{{.AudioChannels}}</td>
+				{{range .Rows}}<tr>
+					{{range .}}<td data-sort="{{.Sort}}">{{range $i, $l := .Lines}}{{if $i}}<br>{{end}}{{$l}}{{end}}</td>
+					{{end}}
 				</tr>{{end}}
 			</tbody>
 		</table>
-		<script src="https://code.jquery.com/jquery-3.3.1.slim.min.js" integrity="sha384-q8i/X+965DzO0rT7abK41JStQIAqVgRVzpbzo5smXKp4YfRvH+8abtTE1Pi6jizo" crossorigin="anonymous"></script>
-		<script src="https://cdnjs.cloudflare.com/ajax/libs/popper.js/1.14.7/umd/popper.min.js" integrity="sha384-UO2eT0CpHqdSJQ6hJty5KVphtPhzWj9WO1clHTMGa3JDZwrnQq4sF86dIHNDz0W1" crossorigin="anonymous"></script>
-		<script src="https://stackpath.bootstrapcdn.com/bootstrap/4.3.1/js/bootstrap.min.js" integrity="sha384-JjSmVgyd0p3pXB1rRibZUAYoIIy6OrQ6VrjIEaFf/nJGzIxFDsf4x0xIM+B07jRM" crossorigin="anonymous"></script>
-		<script src="https://cdnjs.cloudflare.com/ajax/libs/tablesort/5.1.0/tablesort.min.js" integrity="sha256-p3wukcf2d2jxbVnlqPDO9t4AAjnl42D2aIzrK4S0X6w=" crossorigin="anonymous"></script>
-		<script src="https://cdnjs.cloudflare.com/ajax/libs/tablesort/5.1.0/sorts/tablesort.number.min.js" integrity="sha256-ra1pWQ7MfuVIolZ/phcEXegs9m1ehXaCNI8cmc3gJEs=" crossorigin="anonymous"></script>
-		<script>
-		new Tablesort(document.getElementById('probe'));
-		</script>
+		{{script}}
 	</body>
 </html>`
 
 type Probe struct {
-	library string
-	media   []*Media
-	server  *Server
+	cdn        bool
+	columns    []string
+	kind       string
+	level      string
+	libraries  []*Probe
+	library    string
+	libraryKey string
+	media      []*Media
+	server     *Server
+	streams    bool
 }
 
-func (p *Plex) Probe(libraryKey string) (*Probe, error) {
-	lc, err := p.client.GetLibraryContent(libraryKey, "")
+func (p *Plex) Probe(ctx context.Context, libraryKey string) (*Probe, error) {
+	t := newTraversal(ctx, p)
 
-	if err != nil {
+	defer t.cancel()
+
+	var lc plex.SearchResults
+
+	if err := t.fetch(fmt.Sprintf("/library/sections/%s/all", libraryKey), &lc); err != nil {
 		return nil, err
 	}
 
-	media, err := p.probe(lc.MediaContainer.Metadata)
+	media := t.probe(lc.MediaContainer.Metadata)
 
-	if err != nil {
+	if err := t.error(); err != nil {
 		return nil, err
 	}
 
+	for _, m := range media {
+		m.Library = lc.MediaContainer.LibrarySectionTitle
+	}
+
 	return &Probe{
-		library: lc.MediaContainer.LibrarySectionTitle,
-		media:   media,
-		server:  p.server,
+		kind:       libraryKind(lc.MediaContainer.Metadata),
+		library:    lc.MediaContainer.LibrarySectionTitle,
+		libraryKey: libraryKey,
+		media:      media,
+		server:     p.server,
+		streams:    p.streams,
 	}, nil
 }
 
-func (p *Plex) probe(metadata []plex.Metadata) ([]*Media, error) {
-	media := make([]*Media, 0)
+func CombineProbes(probes []*Probe) *Probe {
+	if len(probes) == 1 {
+		return probes[0]
+	}
+
+	c := &Probe{
+		libraries: probes,
+		media:     make([]*Media, 0),
+	}
+
+	keys := make([]string, len(probes))
+	titles := make([]string, len(probes))
+
+	for k, p := range probes {
+		if k == 0 {
+			c.kind = p.kind
+			c.server = p.server
+			c.streams = p.streams
+		} else if c.kind != p.kind {
+			c.kind = "mixed"
+		}
+
+		keys[k] = p.libraryKey
+		titles[k] = p.library
+		c.media = append(c.media, p.media...)
+	}
 
+	c.library = strings.Join(titles, ", ")
+	c.libraryKey = strings.Join(keys, ",")
+
+	return c
+}
+
+func libraryKind(metadata []plex.Metadata) string {
 	for _, m := range metadata {
 		switch m.Type {
-		case "episode", "movie":
-			media = append(media, NewMediaSlice(m)...)
-		case "season":
-			sub, err := p.client.GetEpisodes(m.RatingKey)
+		case "album", "artist", "track":
+			return "music"
+		case "photo", "photoalbum":
+			return "photo"
+		}
+	}
 
-			if err != nil {
-				return media, err
-			}
+	return "video"
+}
 
-			res, err := p.probe(sub.MediaContainer.Metadata)
+type traversal struct {
+	cancel     context.CancelFunc
+	containers int
+	ctx        context.Context
+	err        error
+	items      int
+	mutex      sync.Mutex
+	plex       *Plex
+	sem        chan struct{}
+	started    time.Time
+}
 
-			if err != nil {
-				return media, err
-			}
+// newTraversal walks a library with at most the concurrency of p requests in
+// flight. Its context is cancelled on the first error, or by calling cancel.
+func newTraversal(ctx context.Context, p *Plex) *traversal {
+	ctx, cancel := context.WithCancel(ctx)
 
-			media = append(media, res...)
-		case "show":
-			sub, err := p.client.GetMetadataChildren(m.RatingKey)
+	return &traversal{
+		cancel:  cancel,
+		ctx:     ctx,
+		plex:    p,
+		sem:     make(chan struct{}, p.concurrency),
+		started: time.Now(),
+	}
+}
 
-			if err != nil {
-				return media, err
-			}
+func (t *traversal) error() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.err
+}
+
+func (t *traversal) fail(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 
-			res, err := p.probe(sub.MediaContainer.Metadata)
+	if t.err == nil {
+		t.err = err
+		t.cancel()
+	}
+}
+
+func (t *traversal) acquire() bool {
+	select {
+	case t.sem <- struct{}{}:
+		return true
+	case <-t.ctx.Done():
+		t.fail(t.ctx.Err())
+
+		return false
+	}
+}
+
+func (t *traversal) release() {
+	<-t.sem
+}
+
+func (t *traversal) fetch(path string, v interface{}) error {
+	if !t.acquire() {
+		return t.error()
+	}
+
+	defer t.release()
+
+	return t.plex.request(t.ctx, "GET", path, nil, v)
+}
+
+func (t *traversal) probe(metadata []plex.Metadata) []*Media {
+	results := make([][]*Media, len(metadata))
+
+	var wg sync.WaitGroup
 
-			if err != nil {
-				return media, err
+	for k, m := range metadata {
+		if m.Type == "photo" && len(m.Media) == 0 {
+			m.Type = "photoalbum"
+		}
+
+		switch m.Type {
+		case "clip", "episode", "movie", "photo", "track":
+			results[k] = NewMediaSlice(m)
+
+			t.report(0, len(results[k]))
+
+			if !t.plex.streams {
+				continue
 			}
 
-			media = append(media, res...)
+			wg.Add(1)
+
+			go func(ratingKey string, media []*Media) {
+				defer wg.Done()
+
+				if !t.acquire() {
+					return
+				}
+
+				defer t.release()
+
+				if err := t.plex.loadStreams(t.ctx, ratingKey, media); err != nil {
+					t.fail(err)
+				}
+			}(m.RatingKey, results[k])
+		case "album", "artist", "photoalbum", "season", "show":
+			wg.Add(1)
+
+			go func(k int, ratingKey string) {
+				defer wg.Done()
+
+				var sub plex.MetadataChildren
+
+				if err := t.fetch(fmt.Sprintf("/library/metadata/%s/children", ratingKey), &sub); err != nil {
+					t.fail(err)
+
+					return
+				}
+
+				t.report(1, 0)
+
+				results[k] = t.probe(sub.MediaContainer.Metadata)
+			}(k, m.RatingKey)
 		default:
-			return media, fmt.Errorf("unsupported type \"%s\"", m.Type)
+			t.fail(fmt.Errorf("unsupported type \"%s\"", m.Type))
 		}
 	}
 
-	return media, nil
+	wg.Wait()
+
+	media := make([]*Media, 0)
+
+	for _, res := range results {
+		media = append(media, res...)
+	}
+
+	return media
 }
 
 func (p *Probe) Ascii(w io.Writer) {
 	t := tablewriter.NewWriter(w)
 
-	t.SetHeader([]string{"Title", "Year", "Size", "Quality", "Bit Rate", "Video", "Frame Rate", "Audio", "Channels"})
+	t.SetHeader(p.headers())
 
 	for _, m := range p.media {
-		t.Append([]string{
-			m.Title,
-			strconv.Itoa(m.Year),
-			m.HumanizeSize(),
-			m.Quality,
-			m.HumanizeBitRate(),
-			m.VideoCodec,
-			m.FrameRate,
-			m.AudioCodec,
-			strconv.Itoa(m.AudioChannels),
-		})
+		cells := p.cells(m)
+		row := make([]string, len(cells))
+
+		for k, c := range cells {
+			row[k] = strings.Join(c.Lines, "\n")
+		}
+
+		t.Append(row)
 	}
 
 	t.Render()
 }
 
+func (p *Probe) Csv(w io.Writer) error {
+	c := csv.NewWriter(w)
+	columns := p.recordColumns()
+
+	if err := c.Write(columns); err != nil {
+		return err
+	}
+
+	for _, r := range p.records() {
+		row := make([]string, len(columns))
+
+		for k, column := range columns {
+			row[k] = formatRaw(r.values[column])
+		}
+
+		if err := c.Write(row); err != nil {
+			return err
+		}
+	}
+
+	c.Flush()
+
+	return c.Error()
+}
+
 func (p *Probe) Html(w io.Writer) error {
-	tmpl, err := template.New("probe").Parse(probeTemplate)
+	tmpl, err := template.New("probe").Funcs(htmlFuncs(p.cdn)).Parse(probeTemplate)
 
 	if err != nil {
 		return err
 	}
 
+	rows := make([][]probeCell, len(p.media))
+
+	for k, m := range p.media {
+		rows[k] = p.cells(m)
+	}
+
 	return tmpl.Execute(w, struct {
-		Probe *Probe
+		Headers []string
+		Probe   *Probe
+		Rows    [][]probeCell
+		Summary []probeSummary
 	}{
-		Probe: p,
+		Headers: p.headers(),
+		Probe:   p,
+		Rows:    rows,
+		Summary: p.summary(),
 	})
 }
 
+type probeSummary struct {
+	Label string
+	Value string
+}
+
+// summary takes its totals and averages from the statistics, so that the
+// header agrees with the statistics command and ignores unknown values.
+func (p *Probe) summary() []probeSummary {
+	metrics := p.Statistics().metrics()
+
+	summary := []probeSummary{
+		{Label: "Items", Value: humanize.Comma(int64(len(p.media)))},
+	}
+
+	if p.level == "season" || p.level == "show" {
+		var episodes int
+
+		for _, m := range p.media {
+			episodes += m.Episodes
+		}
+
+		summary[0].Label = strings.Title(p.level) + "s"
+		summary = append(summary, probeSummary{Label: "Episodes", Value: humanize.Comma(int64(episodes))})
+	}
+
+	summary = append(summary, probeSummary{Label: "Total Size", Value: formatMetric("size", *metrics["size"].Total)})
+
+	if p.kind != "photo" {
+		bitrate := "n/a"
+
+		if metrics["bitrate"].Known > 0 {
+			bitrate = formatMetric("bitrate", metrics["bitrate"].Mean)
+		}
+
+		summary = append(
+			summary,
+			probeSummary{Label: "Total Duration", Value: formatMetric("duration", *metrics["duration"].Total)},
+			probeSummary{Label: "Average Bit Rate", Value: bitrate},
+		)
+	}
+
+	return summary
+}
+
+func (p *Probe) Json(w io.Writer) error {
+	e := json.NewEncoder(w)
+
+	e.SetIndent("", "  ")
+
+	return e.Encode(p.records())
+}
+
+func (p *Probe) Combined() bool {
+	return len(p.libraries) > 1
+}
+
+func (p *Probe) Kind() string {
+	return p.kind
+}
+
+func (p *Probe) Libraries() []*Probe {
+	if len(p.libraries) == 0 {
+		return []*Probe{p}
+	}
+
+	return p.libraries
+}
+
 func (p *Probe) Library() string {
 	return p.library
 }
 
+func (p *Probe) LibraryKey() string {
+	return p.libraryKey
+}
+
 func (p *Probe) Media() []*Media {
 	return p.media
 }
@@ -177,3 +431,11 @@ func (p *Probe) Media() []*Media {
 func (p *Probe) Server() *Server {
 	return p.server
 }
+
+func (p *Probe) SetCDN(enabled bool) {
+	p.cdn = enabled
+}
+
+func (p *Probe) Streams() bool {
+	return p.streams
+}
@@ -0,0 +1,19 @@
+package plex
+
+import (
+	"testing"
+)
+
+func TestSummaryIgnoresUnknownBitrates(t *testing.T) {
+	p := &Probe{media: []*Media{{Bitrate: 4000, Size: 1000}, {Bitrate: 0, Size: 500}}}
+
+	for _, s := range p.summary() {
+		if s.Label == "Average Bit Rate" && s.Value != formatMetric("bitrate", 4000) {
+			t.Errorf("expected an average of %s, got %s", formatMetric("bitrate", 4000), s.Value)
+		}
+
+		if s.Label == "Total Size" && s.Value != formatMetric("size", 1500) {
+			t.Errorf("expected a total of %s, got %s", formatMetric("size", 1500), s.Value)
+		}
+	}
+}
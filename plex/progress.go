@@ -0,0 +1,35 @@
+package plex
+
+import (
+	"time"
+)
+
+type Progress struct {
+	Containers int
+	Elapsed    time.Duration
+	Items      int
+}
+
+type ProgressFunc func(Progress)
+
+func (p *Plex) SetProgress(progress ProgressFunc) {
+	p.progress = progress
+}
+
+func (t *traversal) report(containers int, items int) {
+	if t.plex.progress == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.containers += containers
+	t.items += items
+
+	t.plex.progress(Progress{
+		Containers: t.containers,
+		Elapsed:    time.Since(t.started),
+		Items:      t.items,
+	})
+}
@@ -1,10 +1,76 @@
 package plex
 
 import (
+	"errors"
+	"github.com/chzyer/readline"
 	"github.com/jyggen/promptui"
 	"os"
+	"strings"
 )
 
+// PromptForConfirmation asks a yes or no question, and reports false if it is
+// answered with no. Interrupts and other errors are returned.
+func PromptForConfirmation(label string) (bool, error) {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+
+	defer stderrPrompt()()
+
+	if _, err := prompt.Run(); err != nil {
+		if err == promptui.ErrAbort {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func PromptForFormat(formats []string, current string) (string, error) {
+	cursor := 0
+
+	for k, f := range formats {
+		if f == current {
+			cursor = k
+		}
+	}
+
+	prompt := promptui.Select{
+		Label:  "Choose a default output format",
+		Items:  formats,
+		Stdin:  os.Stdin,
+		Stdout: os.Stderr,
+	}
+
+	_, format, err := prompt.RunCursorAt(cursor, 0)
+
+	return format, err
+}
+
+func PromptForToken(current string) (string, error) {
+	prompt := promptui.Prompt{
+		Label:   "Plex access token",
+		Default: current,
+		Mask:    '*',
+		Validate: func(input string) error {
+			if strings.TrimSpace(input) == "" {
+				return errors.New("token must not be empty")
+			}
+
+			return nil
+		},
+	}
+
+	defer stderrPrompt()()
+
+	token, err := prompt.Run()
+
+	return strings.TrimSpace(token), err
+}
+
 func (p *Plex) PromptForLibraryKey() (string, error) {
 	libraries, err := p.client.GetLibraries()
 
@@ -35,7 +101,7 @@ func (p *Plex) PromptForLibraryKey() (string, error) {
 }
 
 func (p *Plex) PromptForServer() (*Server, error) {
-	servers, err := p.client.GetServers()
+	servers, err := p.GetServers()
 
 	if err != nil {
 		return nil, err
@@ -62,3 +128,15 @@ func (p *Plex) PromptForServer() (*Server, error) {
 
 	return p.GetServerByName(name)
 }
+
+// stderrPrompt points readline's default output at stderr, since
+// promptui.Prompt has no Stdout field, to keep stdout clean like the other
+// prompts. The returned func restores it.
+func stderrPrompt() func() {
+	stdout := readline.Stdout
+	readline.Stdout = os.Stderr
+
+	return func() {
+		readline.Stdout = stdout
+	}
+}
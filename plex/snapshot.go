@@ -0,0 +1,283 @@
+package plex
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/olekukonko/tablewriter"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+type Snapshot struct {
+	Created    time.Time           `json:"created"`
+	Library    string              `json:"library"`
+	LibraryKey string              `json:"library_key"`
+	Media      map[string][]*Media `json:"media"`
+	Server     string              `json:"server"`
+	ServerID   string              `json:"server_id"`
+}
+
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+type MediaChange struct {
+	Changes []Change
+	New     *Media
+	Old     *Media
+}
+
+type Diff struct {
+	added   []*Media
+	changed []*MediaChange
+	removed []*Media
+}
+
+func (p *Probe) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Created:    time.Now().UTC(),
+		Library:    p.library,
+		LibraryKey: p.libraryKey,
+		Media:      make(map[string][]*Media, len(p.media)),
+	}
+
+	if p.server != nil {
+		s.Server = p.server.Name
+		s.ServerID = p.server.ClientIdentifier
+	}
+
+	for _, m := range p.media {
+		s.Media[itemKey(m)] = append(s.Media[itemKey(m)], m)
+	}
+
+	return s
+}
+
+// itemKey groups the versions of a movie or episode by the rating key of the
+// item, so that replacing a version is reported as a change to the item.
+func itemKey(m *Media) string {
+	if m.RatingKey == "" {
+		return m.Key()
+	}
+
+	return m.RatingKey
+}
+
+func SnapshotPath(dir string, server *Server, libraryKey string) string {
+	return filepath.Join(dir, server.ClientIdentifier, fmt.Sprintf("%s.json", libraryKey))
+}
+
+func LoadSnapshot(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{}
+
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot \"%s\": %s", path, err)
+	}
+
+	return s, nil
+}
+
+func (s *Snapshot) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (s *Snapshot) Diff(previous *Snapshot) *Diff {
+	d := &Diff{
+		added:   make([]*Media, 0),
+		changed: make([]*MediaChange, 0),
+		removed: make([]*Media, 0),
+	}
+
+	for k, versions := range s.Media {
+		d.compareVersions(previous.Media[k], versions)
+	}
+
+	for k, versions := range previous.Media {
+		if _, ok := s.Media[k]; !ok {
+			d.removed = append(d.removed, versions...)
+		}
+	}
+
+	sort.Slice(d.added, func(i, j int) bool {
+		return d.added[i].SortTitle() < d.added[j].SortTitle()
+	})
+
+	sort.Slice(d.changed, func(i, j int) bool {
+		return d.changed[i].New.SortTitle() < d.changed[j].New.SortTitle()
+	})
+
+	sort.Slice(d.removed, func(i, j int) bool {
+		return d.removed[i].SortTitle() < d.removed[j].SortTitle()
+	})
+
+	return d
+}
+
+// compareVersions pairs the versions of one item by media ID, so that an
+// upgraded file shows up as a change.
+func (d *Diff) compareVersions(before []*Media, after []*Media) {
+	added, removed := pairVersions(before, after, func(a *Media, b *Media) bool {
+		return a.ID == b.ID
+	}, d.compare)
+
+	d.added = append(d.added, added...)
+	d.removed = append(d.removed, removed...)
+}
+
+// pairVersions pairs the versions of one item, first those that are the same
+// and then the rest in order, and calls pair for each pair. Versions left
+// without a counterpart are returned as added or removed.
+func pairVersions(before []*Media, after []*Media, same func(a *Media, b *Media) bool, pair func(before *Media, after *Media)) ([]*Media, []*Media) {
+	added := make([]*Media, 0)
+	paired := make(map[*Media]bool, len(before))
+	removed := make([]*Media, 0)
+	unpaired := make([]*Media, 0, len(after))
+
+	for _, m := range after {
+		var old *Media
+
+		for _, b := range before {
+			if !paired[b] && same(b, m) {
+				old = b
+				break
+			}
+		}
+
+		if old == nil {
+			unpaired = append(unpaired, m)
+			continue
+		}
+
+		paired[old] = true
+		pair(old, m)
+	}
+
+	for _, m := range unpaired {
+		var old *Media
+
+		for _, b := range before {
+			if !paired[b] {
+				old = b
+				break
+			}
+		}
+
+		if old == nil {
+			added = append(added, m)
+			continue
+		}
+
+		paired[old] = true
+		pair(old, m)
+	}
+
+	for _, b := range before {
+		if !paired[b] {
+			removed = append(removed, b)
+		}
+	}
+
+	return added, removed
+}
+
+func (d *Diff) compare(before *Media, after *Media) {
+	if changes := compareMedia(before, after); len(changes) > 0 {
+		d.changed = append(d.changed, &MediaChange{
+			Changes: changes,
+			New:     after,
+			Old:     before,
+		})
+	}
+}
+
+func compareMedia(before *Media, after *Media) []Change {
+	changes := make([]Change, 0)
+
+	if before.Quality != after.Quality {
+		changes = append(changes, Change{Field: "Quality", Old: before.Quality, New: after.Quality})
+	}
+
+	if before.VideoCodec != after.VideoCodec {
+		changes = append(changes, Change{Field: "Video", Old: before.VideoCodec, New: after.VideoCodec})
+	}
+
+	if before.AudioCodec != after.AudioCodec {
+		changes = append(changes, Change{Field: "Audio", Old: before.AudioCodec, New: after.AudioCodec})
+	}
+
+	if before.Size != after.Size {
+		changes = append(changes, Change{Field: "Size", Old: before.HumanizeSize(), New: after.HumanizeSize()})
+	}
+
+	if before.Bitrate != after.Bitrate {
+		changes = append(changes, Change{Field: "Bit Rate", Old: before.HumanizeBitRate(), New: after.HumanizeBitRate()})
+	}
+
+	return changes
+}
+
+func (d *Diff) Added() []*Media {
+	return d.added
+}
+
+func (d *Diff) Changed() []*MediaChange {
+	return d.changed
+}
+
+func (d *Diff) Empty() bool {
+	return len(d.added) == 0 && len(d.changed) == 0 && len(d.removed) == 0
+}
+
+func (d *Diff) Removed() []*Media {
+	return d.removed
+}
+
+func (d *Diff) Ascii(w io.Writer) {
+	t := tablewriter.NewWriter(w)
+
+	t.SetHeader([]string{"Status", "Title", "Field", "Old", "New"})
+
+	for _, m := range d.added {
+		t.Append([]string{"Added", m.Title, "", "", m.HumanizeSize()})
+	}
+
+	for _, m := range d.removed {
+		t.Append([]string{"Removed", m.Title, "", m.HumanizeSize(), ""})
+	}
+
+	for _, c := range d.changed {
+		for _, f := range c.Changes {
+			t.Append([]string{"Changed", c.New.Title, f.Field, f.Old, f.New})
+		}
+	}
+
+	t.Render()
+}
@@ -0,0 +1,33 @@
+package plex
+
+import (
+	"testing"
+)
+
+func TestDiffPairsVersionsOfAnItem(t *testing.T) {
+	before := (&Probe{media: []*Media{
+		{ID: 1, RatingKey: "10", Title: "Movie", Quality: "720p", Size: 1000},
+		{ID: 2, RatingKey: "10", Title: "Movie", Quality: "SD", Size: 500},
+		{ID: 3, RatingKey: "20", Title: "Gone", Size: 100},
+	}}).Snapshot()
+
+	after := (&Probe{media: []*Media{
+		{ID: 2, RatingKey: "10", Title: "Movie", Quality: "SD", Size: 500},
+		{ID: 4, RatingKey: "10", Title: "Movie", Quality: "1080p", Size: 2000},
+		{ID: 5, RatingKey: "30", Title: "New", Size: 100},
+	}}).Snapshot()
+
+	d := after.Diff(before)
+
+	if len(d.Added()) != 1 || d.Added()[0].Title != "New" {
+		t.Errorf("expected only \"New\" to be added, got %+v", d.Added())
+	}
+
+	if len(d.Removed()) != 1 || d.Removed()[0].Title != "Gone" {
+		t.Errorf("expected only \"Gone\" to be removed, got %+v", d.Removed())
+	}
+
+	if len(d.Changed()) != 1 || d.Changed()[0].Old.ID != 1 || d.Changed()[0].New.ID != 4 {
+		t.Fatalf("expected the upgrade to be a change, got %+v", d.Changed())
+	}
+}
@@ -1,80 +1,138 @@
 package plex
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"github.com/dustin/go-humanize"
 	"github.com/olekukonko/tablewriter"
+	"html/template"
 	"io"
 	"math"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const statisticsTemplate = `<!doctype html>
+<html lang="en">
+	<head>
+		<meta charset="utf-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+		{{stylesheet}}
+		<title>Statistics: {{.Library}} @ {{.Server}}</title>
+	</head>
+	<body>
+		<h1>{{.Library}} @ {{.Server}}</h1>
+		{{range .Sections}}{{if .Title}}<h2>{{.Title}}</h2>
+		{{end}}<table class="table table-striped table-sm sortable">
+			<thead class="thead-dark">
+				<tr>
+					{{range .MetricHeader}}<th scope="col">{{.}}</th>{{end}}
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Metrics}}<tr>
+					{{range .}}<td>{{.}}</td>{{end}}
+				</tr>{{end}}
+			</tbody>
+		</table>
+		{{range .Histograms}}<table class="table table-striped table-sm">
+			<thead class="thead-dark">
+				<tr>
+					<th scope="col">{{.Title}}</th>
+					<th scope="col">Count</th>
+					<th scope="col">Percentage</th>
+					<th scope="col" class="w-50">Distribution</th>
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Buckets}}<tr>
+					<td>{{.Label}}</td>
+					<td>{{.Count}}</td>
+					<td>{{printf "%.2f" .Percentage}}%</td>
+					<td><div class="bar" style="width: {{printf "%.2f" .Width}}%"></div></td>
+				</tr>{{end}}
+			</tbody>
+		</table>
+		{{end}}		{{range .Breakdowns}}<table class="table table-striped table-sm sortable">
+			<thead class="thead-dark">
+				<tr>
+					<th scope="col">{{.Title}}</th>
+					<th scope="col">Count</th>
+					<th scope="col">Percentage</th>
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Counts}}<tr>
+					<td>{{.Value}}</td>
+					<td>{{.Count}}</td>
+					<td>{{printf "%.2f" .Percentage}}%</td>
+				</tr>{{end}}
+			</tbody>
+		</table>
+		{{end}}{{range .Groups}}<table class="table table-striped table-sm sortable">
+			<thead class="thead-dark">
+				<tr>
+					{{range .Header}}<th scope="col">{{.}}</th>{{end}}
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Rows}}<tr>
+					{{range .}}<td>{{.}}</td>{{end}}
+				</tr>{{end}}
+			</tbody>
+		</table>
+		{{end}}{{end}}
+		{{script}}
+	</body>
+</html>`
+
 type Statistics struct {
-	probe         *Probe
 	audioChannels map[int]int
 	audioCodec    map[string]int
-	bitrate       struct {
-		minimum uint64
-		mean    uint64
-		median  uint64
-		maximum uint64
-		total   uint64
-	}
-	duration struct {
-		minimum time.Duration
-		mean    time.Duration
-		median  time.Duration
-		maximum time.Duration
-		total   time.Duration
-	}
-	quality map[string]int
-	rating  struct {
-		minimum float64
-		mean    float64
-		median  float64
-		maximum float64
-		total   float64
-	}
-	size struct {
-		minimum uint64
-		mean    uint64
-		median  uint64
-		maximum uint64
-		total   uint64
-	}
-	total      int
-	videoCodec map[string]int
-	year       struct {
-		minimum int
-		mean    int
-		median  int
-		maximum int
-		total   int
-	}
+	container     map[string]int
+	groupBy       []string
+	groups        []statisticsGroup
+	histograms    bool
+	libraries     []*Statistics
+	missing       map[string]int
+	percentiles   []float64
+	probe         *Probe
+	quality       map[string]int
+	total         int
+	values        map[string][]float64
+	videoCodec    map[string]int
+}
+
+var statisticsValues = map[string]func(m *Media) float64{
+	"bitrate":  func(m *Media) float64 { return float64(m.Bitrate) },
+	"duration": func(m *Media) float64 { return float64(m.Duration) },
+	"rating":   func(m *Media) float64 { return m.Rating },
+	"size":     func(m *Media) float64 { return float64(m.Size) },
+	"year":     func(m *Media) float64 { return float64(m.Year) },
 }
 
 func (p *Probe) Statistics() *Statistics {
-	s := &Statistics{}
+	s := &Statistics{probe: p}
+
+	if p.Combined() {
+		for _, l := range p.libraries {
+			s.libraries = append(s.libraries, l.Statistics())
+		}
+	}
 
 	s.audioChannels = make(map[int]int, 0)
 	s.audioCodec = make(map[string]int, 0)
-	s.bitrate.minimum = math.MaxUint64
-	s.duration.minimum = 24 * 365 * time.Hour
+	s.container = make(map[string]int, 0)
+	s.missing = make(map[string]int, len(statisticsValues))
 	s.quality = make(map[string]int, 0)
-	s.rating.minimum = math.MaxFloat64
-	s.size.minimum = math.MaxUint64
 	s.total = len(p.media)
+	s.values = make(map[string][]float64, len(statisticsValues))
 	s.videoCodec = make(map[string]int, 0)
-	s.year.minimum = math.MaxUint16
 
-	bitrates := make([]uint64, s.total)
-	durations := make([]time.Duration, s.total)
-	ratings := make([]float64, s.total)
-	sizes := make([]uint64, s.total)
-	years := make([]int, s.total)
-
-	for k, v := range p.media {
+	for _, v := range p.media {
 		if _, ok := s.audioChannels[v.AudioChannels]; !ok {
 			s.audioChannels[v.AudioChannels] = 0
 		}
@@ -87,6 +145,12 @@ func (p *Probe) Statistics() *Statistics {
 
 		s.audioCodec[v.AudioCodec] += 1
 
+		if _, ok := s.container[v.Container()]; !ok {
+			s.container[v.Container()] = 0
+		}
+
+		s.container[v.Container()] += 1
+
 		if _, ok := s.quality[v.Quality]; !ok {
 			s.quality[v.Quality] = 0
 		}
@@ -98,215 +162,591 @@ func (p *Probe) Statistics() *Statistics {
 		}
 
 		s.videoCodec[v.VideoCodec] += 1
+	}
 
-		bitrates[k] = v.Bitrate
-		durations[k] = v.Duration
-		ratings[k] = v.Rating
-		sizes[k] = v.Size
-		years[k] = v.Year
+	for k := range statisticsValues {
+		s.values[k], s.missing[k] = knownValues(k, p.media)
+	}
 
-		s.bitrate.total += v.Bitrate
-		s.duration.total += v.Duration
-		s.rating.total += v.Rating
-		s.size.total += v.Size
-		s.year.total += v.Year
+	return s
+}
 
-		if v.Bitrate > s.bitrate.maximum {
-			s.bitrate.maximum = v.Bitrate
+// knownValues returns the values of a metric for the given media. Plex
+// reports unknown ratings, years, bitrates, durations and sizes as zero, so
+// those are counted as missing instead of dragging the metrics down.
+func knownValues(key string, media []*Media) ([]float64, int) {
+	values := make([]float64, 0, len(media))
+	missing := 0
+
+	for _, m := range media {
+		if v := statisticsValues[key](m); v > 0 {
+			values = append(values, v)
+		} else {
+			missing++
 		}
+	}
 
-		if v.Bitrate < s.bitrate.minimum {
-			s.bitrate.minimum = v.Bitrate
-		}
+	return values, missing
+}
+
+func (s *Statistics) SetPercentiles(percentiles []string) error {
+	s.percentiles = make([]float64, 0, len(percentiles))
 
-		if v.Duration > s.duration.maximum {
-			s.duration.maximum = v.Duration
+	for _, p := range percentiles {
+		p = strings.ToLower(strings.TrimSpace(p))
+
+		if p == "" {
+			continue
 		}
 
-		if v.Duration < s.duration.minimum {
-			s.duration.minimum = v.Duration
+		value, err := strconv.ParseFloat(strings.TrimPrefix(p, "p"), 64)
+
+		if err != nil || value <= 0 || value >= 100 {
+			return fmt.Errorf("\"%s\" is not a valid percentile, expected a number between 0 and 100 such as p90", p)
 		}
 
-		if v.Rating > s.rating.maximum {
-			s.rating.maximum = v.Rating
+		s.percentiles = append(s.percentiles, value)
+	}
+
+	sort.Float64s(s.percentiles)
+
+	for _, l := range s.libraries {
+		if err := l.SetPercentiles(percentiles); err != nil {
+			return err
 		}
+	}
 
-		if v.Rating < s.rating.minimum {
-			s.rating.minimum = v.Rating
+	return s.SetGroupBy(s.groupBy)
+}
+
+func (s *Statistics) SetHistograms(enabled bool) {
+	s.histograms = enabled
+
+	for _, l := range s.libraries {
+		l.SetHistograms(enabled)
+	}
+}
+
+func newStatisticsMetric(key string, values []float64, missing int, percentiles []float64) statisticsMetric {
+	m := statisticsMetric{Known: len(values), Missing: missing}
+
+	var total float64
+
+	if key == "duration" || key == "size" {
+		m.Total = &total
+	}
+
+	if len(values) == 0 {
+		return m
+	}
+
+	sorted := append([]float64{}, values...)
+
+	sort.Float64s(sorted)
+
+	for _, v := range sorted {
+		total += v
+	}
+
+	m.Minimum = sorted[0]
+	m.Mean = total / float64(len(sorted))
+	m.Median = percentile(sorted, 50)
+	m.Maximum = sorted[len(sorted)-1]
+
+	if len(percentiles) > 0 {
+		m.Percentiles = make(map[string]float64, len(percentiles))
+
+		for _, p := range percentiles {
+			m.Percentiles[percentileKey(p)] = percentile(sorted, p)
 		}
+	}
+
+	return m
+}
+
+// percentile interpolates linearly between the two closest ranks of an
+// already sorted slice, which for p = 50 yields the usual median.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func percentileKey(p float64) string {
+	return "p" + formatFloat(p)
+}
 
-		if v.Size > s.size.maximum {
-			s.size.maximum = v.Size
+func (s *Statistics) metricHeader(title string) []string {
+	header := []string{title, "Min", "Mean", "Median"}
+
+	for _, p := range s.percentiles {
+		header = append(header, strings.ToUpper(percentileKey(p)))
+	}
+
+	return append(header, "Max", "Total", "Known / Missing")
+}
+
+func (s *Statistics) metricRow(title string, key string, m statisticsMetric) []string {
+	value := func(v float64) string {
+		if m.Known == 0 {
+			return "n/a"
 		}
 
-		if v.Size < s.size.minimum {
-			s.size.minimum = v.Size
+		return formatMetric(key, v)
+	}
+
+	row := []string{
+		title,
+		value(m.Minimum),
+		value(m.Mean),
+		value(m.Median),
+	}
+
+	for _, p := range s.percentiles {
+		row = append(row, value(m.Percentiles[percentileKey(p)]))
+	}
+
+	total := "n/a"
+
+	if m.Total != nil {
+		total = formatMetric(key, *m.Total)
+	}
+
+	return append(row, value(m.Maximum), total, fmt.Sprintf("%d / %d", m.Known, m.Missing))
+}
+
+func formatMetric(key string, v float64) string {
+	switch key {
+	case "bitrate":
+		return humanize.Bytes(uint64(math.Round(v))) + "ps"
+	case "duration":
+		return humanizeDuration(time.Duration(math.Round(v)))
+	case "rating":
+		return fmt.Sprintf("%.2f", v)
+	case "size":
+		return humanize.Bytes(uint64(math.Round(v)))
+	default:
+		return fmt.Sprintf("%d", int(math.Round(v)))
+	}
+}
+
+type statisticsBreakdown struct {
+	Counts []statisticsCount `json:"counts"`
+	Key    string            `json:"-"`
+	Title  string            `json:"-"`
+}
+
+type statisticsCount struct {
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+	Value      string  `json:"value"`
+}
+
+type statisticsMetric struct {
+	Minimum     float64            `json:"minimum"`
+	Mean        float64            `json:"mean"`
+	Median      float64            `json:"median"`
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+	Maximum     float64            `json:"maximum"`
+	Total       *float64           `json:"total"`
+	Known       int                `json:"known"`
+	Missing     int                `json:"missing"`
+}
+
+func (s *Statistics) breakdowns() []statisticsBreakdown {
+	audioChannels := make(map[string]int, len(s.audioChannels))
+
+	for k, v := range s.audioChannels {
+		if k == 0 {
+			audioChannels[""] = v
+		} else {
+			audioChannels[strconv.Itoa(k)] = v
 		}
+	}
 
-		if v.Year > s.year.maximum {
-			s.year.maximum = v.Year
+	switch s.probe.Kind() {
+	case "music":
+		return []statisticsBreakdown{
+			s.breakdown("audio_channels", "Audio Channels", audioChannels),
+			s.breakdown("audio_codec", "Audio Codec", s.audioCodec),
+			s.breakdown("container", "Container", s.container),
+		}
+	case "photo":
+		return []statisticsBreakdown{
+			s.breakdown("container", "Container", s.container),
+		}
+	default:
+		return []statisticsBreakdown{
+			s.breakdown("audio_channels", "Audio Channels", audioChannels),
+			s.breakdown("audio_codec", "Audio Codec", s.audioCodec),
+			s.breakdown("quality", "Quality", s.quality),
+			s.breakdown("video_codec", "Video Codec", s.videoCodec),
 		}
+	}
+}
 
-		if v.Year < s.year.minimum {
-			s.year.minimum = v.Year
+func (s *Statistics) metricKeys() []string {
+	switch s.probe.Kind() {
+	case "music":
+		return []string{"bitrate", "duration", "size", "year"}
+	case "photo":
+		return []string{"size", "year"}
+	default:
+		return []string{"bitrate", "duration", "rating", "size", "year"}
+	}
+}
+
+func (s *Statistics) breakdown(key string, title string, counts map[string]int) statisticsBreakdown {
+	b := statisticsBreakdown{
+		Counts: make([]statisticsCount, 0, len(counts)),
+		Key:    key,
+		Title:  title,
+	}
+
+	for k, v := range counts {
+		if k == "" {
+			k = "unknown"
 		}
+
+		b.Counts = append(b.Counts, statisticsCount{
+			Count:      v,
+			Percentage: float64(v) / float64(s.total) * 100,
+			Value:      k,
+		})
 	}
 
-	s.bitrate.mean = uint64(math.Round(float64(s.bitrate.total) / float64(s.total)))
-	s.duration.mean = time.Duration(math.Round(float64(s.duration.total) / float64(s.total)))
-	s.rating.mean = s.rating.total / float64(s.total)
-	s.size.mean = uint64(math.Round(float64(s.size.total) / float64(s.total)))
-	s.year.mean = int(math.Round(float64(s.year.total) / float64(s.total)))
+	sort.Slice(b.Counts, func(i, j int) bool {
+		if b.Counts[i].Count != b.Counts[j].Count {
+			return b.Counts[i].Count > b.Counts[j].Count
+		}
 
-	sort.Slice(bitrates, func(i, j int) bool {
-		return bitrates[i] > bitrates[j]
+		return b.Counts[i].Value < b.Counts[j].Value
 	})
 
-	sort.Slice(durations, func(i, j int) bool {
-		return durations[i] > durations[j]
-	})
+	return b
+}
 
-	sort.Slice(ratings, func(i, j int) bool {
-		return ratings[i] > ratings[j]
-	})
+func (s *Statistics) metrics() map[string]statisticsMetric {
+	metrics := make(map[string]statisticsMetric, 0)
 
-	sort.Slice(sizes, func(i, j int) bool {
-		return sizes[i] > sizes[j]
-	})
+	for _, k := range s.metricKeys() {
+		metrics[k] = newStatisticsMetric(k, s.values[k], s.missing[k], s.percentiles)
+	}
 
-	sort.Slice(years, func(i, j int) bool {
-		return years[i] > years[j]
-	})
+	return metrics
+}
 
-	h := s.total / 2
+func (s *Statistics) metricRows() [][]string {
+	metrics := s.metrics()
+	keys := s.metricKeys()
+	rows := make([][]string, len(keys))
 
-	if s.total%2 == 0 {
-		s.bitrate.median = bitrates[h]
-		s.duration.median = durations[h]
-		s.rating.median = ratings[h]
-		s.size.median = sizes[h]
-		s.year.median = years[h]
-	} else {
-		s.bitrate.median = (bitrates[h-1] + bitrates[h]) / 2
-		s.duration.median = (durations[h-1] + durations[h]) / 2
-		s.rating.median = (ratings[h-1] + ratings[h]) / 2
-		s.size.median = (sizes[h-1] + sizes[h]) / 2
-		s.year.median = (years[h-1] + years[h]) / 2
+	for k, key := range keys {
+		rows[k] = s.metricRow(strings.Title(key), key, metrics[key])
 	}
 
-	return s
+	return rows
+}
+
+func (s *Statistics) sections() []*Statistics {
+	return append([]*Statistics{s}, s.libraries...)
+}
+
+func (s *Statistics) title() string {
+	if s.probe.Combined() {
+		return "All libraries"
+	}
+
+	return s.probe.Library()
 }
 
 func (s *Statistics) Ascii(w io.Writer) {
+	for k, section := range s.sections() {
+		if s.probe.Combined() {
+			if k > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+
+			_, _ = fmt.Fprintln(w, section.title())
+		}
+
+		section.asciiTables(w)
+	}
+}
+
+func (s *Statistics) asciiTables(w io.Writer) {
 	t := tablewriter.NewWriter(w)
 
 	t.SetAlignment(tablewriter.ALIGN_CENTER)
-	t.SetHeader([]string{"Type", "Min", "Mean", "Median", "Max", "Total"})
-
-	t.Append([]string{
-		"Bitrate",
-		humanize.Bytes(s.bitrate.minimum) + "ps",
-		humanize.Bytes(s.bitrate.mean) + "ps",
-		humanize.Bytes(s.bitrate.median) + "ps",
-		humanize.Bytes(s.bitrate.maximum) + "ps",
-		"n/a",
-	})
+	t.SetHeader(s.metricHeader("Type"))
+	t.AppendBulk(s.metricRows())
+	t.Render()
 
-	t.Append([]string{
-		"Rating",
-		fmt.Sprintf("%.2f", s.rating.minimum),
-		fmt.Sprintf("%.2f", s.rating.mean),
-		fmt.Sprintf("%.2f", s.rating.median),
-		fmt.Sprintf("%.2f", s.rating.maximum),
-		"n/a",
-	})
+	for _, h := range s.histogramTables() {
+		t = tablewriter.NewWriter(w)
 
-	t.Append([]string{
-		"Duration",
-		humanizeDuration(s.duration.minimum),
-		humanizeDuration(s.duration.mean),
-		humanizeDuration(s.duration.median),
-		humanizeDuration(s.duration.maximum),
-		humanizeDuration(s.duration.total),
-	})
+		t.SetAlignment(tablewriter.ALIGN_LEFT)
+		t.SetHeader([]string{h.Title, "Count", "Percentage", "Distribution"})
 
-	t.Append([]string{
-		"Size",
-		humanize.Bytes(s.size.minimum),
-		humanize.Bytes(s.size.mean),
-		humanize.Bytes(s.size.median),
-		humanize.Bytes(s.size.maximum),
-		humanize.Bytes(s.size.total),
-	})
+		for _, b := range h.Buckets {
+			t.Append([]string{
+				b.Label,
+				fmt.Sprintf("%d", b.Count),
+				fmt.Sprintf("%.2f%%", b.Percentage),
+				b.bar(40),
+			})
+		}
 
-	t.Append([]string{
-		"Year",
-		fmt.Sprintf("%d", s.year.minimum),
-		fmt.Sprintf("%d", s.year.mean),
-		fmt.Sprintf("%d", s.year.median),
-		fmt.Sprintf("%d", s.year.maximum),
-		"n/a",
-	})
+		t.Render()
+	}
 
-	t.Render()
+	for _, b := range s.breakdowns() {
+		t = tablewriter.NewWriter(w)
 
-	t = tablewriter.NewWriter(w)
+		t.SetAlignment(tablewriter.ALIGN_CENTER)
+		t.SetHeader([]string{b.Title, "Count", "Percentage"})
 
-	t.SetAlignment(tablewriter.ALIGN_CENTER)
-	t.SetHeader([]string{"Audio Channels", "Count", "Percentage"})
+		for _, c := range b.Counts {
+			t.Append([]string{
+				c.Value,
+				fmt.Sprintf("%d", c.Count),
+				fmt.Sprintf("%.2f%%", c.Percentage),
+			})
+		}
 
-	for k, v := range s.audioChannels {
-		t.Append([]string{
-			fmt.Sprintf("%d", k),
-			fmt.Sprintf("%d", v),
-			fmt.Sprintf("%.2f%%", float64(v)/float64(s.total)*100),
-		})
+		t.Render()
 	}
 
-	t.Render()
+	for _, g := range s.groupTables() {
+		t = tablewriter.NewWriter(w)
 
-	t = tablewriter.NewWriter(w)
+		t.SetAlignment(tablewriter.ALIGN_CENTER)
+		t.SetHeader(g.Header)
+		t.AppendBulk(g.Rows)
+		t.Render()
+	}
+}
 
-	t.SetAlignment(tablewriter.ALIGN_CENTER)
-	t.SetHeader([]string{"Audio Codec", "Count", "Percentage"})
+func (s *Statistics) Csv(w io.Writer) error {
+	c := csv.NewWriter(w)
 
-	for k, v := range s.audioCodec {
-		t.Append([]string{
-			k,
-			fmt.Sprintf("%d", v),
-			fmt.Sprintf("%.2f%%", float64(v)/float64(s.total)*100),
-		})
+	header := []string{"type", "value", "minimum", "mean", "median", "maximum", "total", "count", "percentage", "library", "group", "size_percentage", "known", "missing"}
+
+	for _, p := range s.percentiles {
+		header = append(header, percentileKey(p))
 	}
 
-	t.Render()
+	if err := c.Write(header); err != nil {
+		return err
+	}
 
-	t = tablewriter.NewWriter(w)
+	for _, section := range s.sections() {
+		if err := section.csvRows(c); err != nil {
+			return err
+		}
+	}
 
-	t.SetAlignment(tablewriter.ALIGN_CENTER)
-	t.SetHeader([]string{"Quality", "Count", "Percentage"})
+	c.Flush()
 
-	for k, v := range s.quality {
-		t.Append([]string{
-			k,
-			fmt.Sprintf("%d", v),
-			fmt.Sprintf("%.2f%%", float64(v)/float64(s.total)*100),
-		})
+	return c.Error()
+}
+
+func (s *Statistics) csvRows(c *csv.Writer) error {
+	library := ""
+
+	if !s.probe.Combined() {
+		library = s.probe.Library()
 	}
 
-	t.Render()
+	metrics := s.metrics()
 
-	t = tablewriter.NewWriter(w)
+	for _, k := range s.metricKeys() {
+		m := metrics[k]
+		row := append([]string{k, ""}, csvMetricValues(m)...)
+		row = append(row, "", "", library, "", "")
 
-	t.SetAlignment(tablewriter.ALIGN_CENTER)
-	t.SetHeader([]string{"Video Codec", "Count", "Percentage"})
+		if err := c.Write(append(row, s.csvMetricCounts(&m)...)); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range s.breakdowns() {
+		for _, v := range b.Counts {
+			if err := c.Write(append([]string{b.Key, v.Value, "", "", "", "", "", strconv.Itoa(v.Count), formatFloat(v.Percentage), library, "", ""}, s.csvMetricCounts(nil)...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, h := range s.histogramTables() {
+		for _, b := range h.Buckets {
+			row := []string{h.Key + "_histogram", b.Label, formatFloat(b.Minimum), "", "", formatFloat(b.Maximum), "", strconv.Itoa(b.Count), formatFloat(b.Percentage), library, "", ""}
+
+			if err := c.Write(append(row, s.csvMetricCounts(nil)...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, g := range s.groups {
+		row := []string{"group", g.Value, "", "", "", "", strconv.FormatUint(g.Size, 10), strconv.Itoa(g.Count), formatFloat(g.Percentage), library, g.Value, formatFloat(g.SizePercentage)}
+
+		if err := c.Write(append(row, s.csvMetricCounts(nil)...)); err != nil {
+			return err
+		}
+
+		for _, k := range s.metricKeys() {
+			m := g.Metrics[k]
+			row := append([]string{k, ""}, csvMetricValues(m)...)
+			row = append(row, strconv.Itoa(g.Count), formatFloat(g.Percentage), library, g.Value, formatFloat(g.SizePercentage))
+
+			if err := c.Write(append(row, s.csvMetricCounts(&m)...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// csvMetricValues returns the minimum, mean, median, maximum and total
+// columns of a metric, left empty when none of its values are known.
+func csvMetricValues(m statisticsMetric) []string {
+	values := make([]string, 5)
+
+	if m.Known > 0 {
+		values[0] = formatFloat(m.Minimum)
+		values[1] = formatFloat(m.Mean)
+		values[2] = formatFloat(m.Median)
+		values[3] = formatFloat(m.Maximum)
+	}
+
+	if m.Total != nil {
+		values[4] = formatFloat(*m.Total)
+	}
+
+	return values
+}
+
+func (s *Statistics) csvMetricCounts(m *statisticsMetric) []string {
+	cells := make([]string, 2+len(s.percentiles))
+
+	if m == nil {
+		return cells
+	}
+
+	cells[0] = strconv.Itoa(m.Known)
+	cells[1] = strconv.Itoa(m.Missing)
+
+	if m.Percentiles == nil {
+		return cells
+	}
+
+	for k, p := range s.percentiles {
+		cells[2+k] = formatFloat(m.Percentiles[percentileKey(p)])
+	}
+
+	return cells
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+type statisticsSection struct {
+	Breakdowns   []statisticsBreakdown
+	Groups       []statisticsGroupTable
+	Histograms   []statisticsHistogram
+	MetricHeader []string
+	Metrics      [][]string
+	Title        string
+}
+
+func (s *Statistics) Html(w io.Writer) error {
+	tmpl, err := template.New("statistics").Funcs(htmlFuncs(s.probe.cdn)).Parse(statisticsTemplate)
 
-	for k, v := range s.videoCodec {
-		t.Append([]string{
-			k,
-			fmt.Sprintf("%d", v),
-			fmt.Sprintf("%.2f%%", float64(v)/float64(s.total)*100),
+	if err != nil {
+		return err
+	}
+
+	sections := make([]statisticsSection, 0)
+
+	for _, section := range s.sections() {
+		title := ""
+
+		if s.probe.Combined() {
+			title = section.title()
+		}
+
+		sections = append(sections, statisticsSection{
+			Breakdowns:   section.breakdowns(),
+			Groups:       section.groupTables(),
+			Histograms:   section.histogramTables(),
+			MetricHeader: section.metricHeader("Type"),
+			Metrics:      section.metricRows(),
+			Title:        title,
 		})
 	}
 
-	t.Render()
+	return tmpl.Execute(w, struct {
+		Library  string
+		Sections []statisticsSection
+		Server   string
+	}{
+		Library:  s.probe.Library(),
+		Sections: sections,
+		Server:   s.probe.Server().Name,
+	})
+}
+
+type statisticsReport struct {
+	Breakdowns map[string][]statisticsCount  `json:"breakdowns"`
+	GroupBy    []string                      `json:"group_by,omitempty"`
+	Groups     []statisticsGroup             `json:"groups,omitempty"`
+	Histograms map[string][]statisticsBucket `json:"histograms,omitempty"`
+	Libraries  []statisticsReport            `json:"libraries,omitempty"`
+	Library    string                        `json:"library"`
+	Metrics    map[string]statisticsMetric   `json:"metrics"`
+	Server     string                        `json:"server"`
+	Total      int                           `json:"total"`
+}
+
+func (s *Statistics) report() statisticsReport {
+	r := statisticsReport{
+		Breakdowns: make(map[string][]statisticsCount, 0),
+		GroupBy:    s.groupBy,
+		Groups:     s.groups,
+		Library:    s.probe.Library(),
+		Metrics:    s.metrics(),
+		Server:     s.probe.Server().Name,
+		Total:      s.total,
+	}
+
+	for _, b := range s.breakdowns() {
+		r.Breakdowns[b.Key] = b.Counts
+	}
+
+	for _, h := range s.histogramTables() {
+		if r.Histograms == nil {
+			r.Histograms = make(map[string][]statisticsBucket, 0)
+		}
+
+		r.Histograms[h.Key] = h.Buckets
+	}
+
+	for _, l := range s.libraries {
+		r.Libraries = append(r.Libraries, l.report())
+	}
+
+	return r
+}
+
+func (s *Statistics) Json(w io.Writer) error {
+	e := json.NewEncoder(w)
+
+	e.SetIndent("", "  ")
+
+	return e.Encode(s.report())
 }
@@ -0,0 +1,194 @@
+package plex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	streamTypeVideo    = 1
+	streamTypeAudio    = 2
+	streamTypeSubtitle = 3
+)
+
+type Stream struct {
+	BitDepth     int
+	Channels     int
+	Codec        string
+	ColorSpace   string
+	Default      bool
+	DynamicRange string
+	Forced       bool
+	Language     string
+	LanguageCode string
+	SamplingRate int
+}
+
+type streamMetadata struct {
+	BitDepth       int    `json:"bitDepth"`
+	Channels       int    `json:"channels"`
+	Codec          string `json:"codec"`
+	ColorPrimaries string `json:"colorPrimaries"`
+	ColorSpace     string `json:"colorSpace"`
+	ColorTrc       string `json:"colorTrc"`
+	Default        bool   `json:"default"`
+	DOVIPresent    bool   `json:"DOVIPresent"`
+	Forced         bool   `json:"forced"`
+	Language       string `json:"language"`
+	LanguageCode   string `json:"languageCode"`
+	SamplingRate   int    `json:"samplingRate"`
+	StreamType     int    `json:"streamType"`
+}
+
+type streamContainer struct {
+	MediaContainer struct {
+		Metadata []struct {
+			Media []struct {
+				ID   int `json:"id"`
+				Part []struct {
+					Stream []streamMetadata `json:"Stream"`
+				} `json:"Part"`
+			} `json:"Media"`
+		} `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+func newStream(s streamMetadata) *Stream {
+	return &Stream{
+		BitDepth:     s.BitDepth,
+		Channels:     s.Channels,
+		Codec:        s.Codec,
+		ColorSpace:   s.ColorSpace,
+		Default:      s.Default,
+		DynamicRange: dynamicRange(s),
+		Forced:       s.Forced,
+		Language:     s.Language,
+		LanguageCode: s.LanguageCode,
+		SamplingRate: s.SamplingRate,
+	}
+}
+
+func dynamicRange(s streamMetadata) string {
+	if s.StreamType != streamTypeVideo {
+		return ""
+	}
+
+	switch {
+	case s.DOVIPresent:
+		return "Dolby Vision"
+	case s.ColorTrc == "smpte2084":
+		return "HDR10"
+	case s.ColorTrc == "arib-std-b67":
+		return "HLG"
+	default:
+		return "SDR"
+	}
+}
+
+func (p *Plex) loadStreams(ctx context.Context, ratingKey string, media []*Media) error {
+	var sc streamContainer
+
+	if err := p.request(ctx, "GET", fmt.Sprintf("/library/metadata/%s", ratingKey), nil, &sc); err != nil {
+		return err
+	}
+
+	byID := make(map[int]*Media, len(media))
+
+	for _, m := range media {
+		byID[m.ID] = m
+	}
+
+	for _, item := range sc.MediaContainer.Metadata {
+		for _, v := range item.Media {
+			m, ok := byID[v.ID]
+
+			if !ok {
+				continue
+			}
+
+			m.AudioStreams = make([]*Stream, 0)
+			m.SubtitleStreams = make([]*Stream, 0)
+			m.VideoStreams = make([]*Stream, 0)
+
+			for _, part := range v.Part {
+				for _, s := range part.Stream {
+					switch s.StreamType {
+					case streamTypeAudio:
+						m.AudioStreams = append(m.AudioStreams, newStream(s))
+					case streamTypeSubtitle:
+						m.SubtitleStreams = append(m.SubtitleStreams, newStream(s))
+					case streamTypeVideo:
+						m.VideoStreams = append(m.VideoStreams, newStream(s))
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Media) AudioLanguages() []string {
+	return streamLanguages(m.AudioStreams)
+}
+
+func (m *Media) BitDepth() int {
+	if len(m.VideoStreams) > 0 {
+		return m.VideoStreams[0].BitDepth
+	}
+
+	if len(m.AudioStreams) > 0 {
+		return m.AudioStreams[0].BitDepth
+	}
+
+	return 0
+}
+
+func (m *Media) DynamicRange() string {
+	if len(m.VideoStreams) == 0 {
+		return ""
+	}
+
+	return m.VideoStreams[0].DynamicRange
+}
+
+func (m *Media) SamplingRate() int {
+	if len(m.AudioStreams) == 0 {
+		return 0
+	}
+
+	return m.AudioStreams[0].SamplingRate
+}
+
+func (m *Media) SubtitleLanguages() []string {
+	return streamLanguages(m.SubtitleStreams)
+}
+
+func streamLanguages(streams []*Stream) []string {
+	seen := make(map[string]bool, len(streams))
+	languages := make([]string, 0, len(streams))
+
+	for _, s := range streams {
+		language := s.LanguageCode
+
+		if language == "" {
+			language = "und"
+		}
+
+		if s.Forced {
+			language += " (forced)"
+		}
+
+		if !seen[language] {
+			seen[language] = true
+			languages = append(languages, language)
+		}
+	}
+
+	return languages
+}
+
+func joinLanguages(languages []string) string {
+	return strings.Join(languages, ", ")
+}
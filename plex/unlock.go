@@ -0,0 +1,112 @@
+package plex
+
+import (
+	"context"
+	"fmt"
+	"github.com/jrudio/go-plex-client"
+	"github.com/olekukonko/tablewriter"
+	"io"
+	"net/url"
+	"strings"
+)
+
+type LockedItem struct {
+	Fields    []string
+	RatingKey string
+	Title     string
+	Type      string
+}
+
+var fieldAliases = map[string]string{
+	"background":   "art",
+	"poster":       "thumb",
+	"release-date": "originallyAvailableAt",
+	"sort-title":   "titleSort",
+}
+
+func NormalizeField(field string) string {
+	field = strings.TrimSpace(field)
+
+	if f, ok := fieldAliases[strings.ToLower(field)]; ok {
+		return f
+	}
+
+	return field
+}
+
+func (p *Plex) LockedItems(ctx context.Context, libraryKey string, fields []string) ([]*LockedItem, error) {
+	metadata, err := p.walkLibrary(ctx, libraryKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	only := make(map[string]bool, len(fields))
+
+	for _, f := range fields {
+		only[NormalizeField(f)] = true
+	}
+
+	items := make([]*LockedItem, 0)
+
+	for _, m := range metadata {
+		locked := make([]string, 0)
+
+		for _, f := range m.Field {
+			if f.Locked && (len(only) == 0 || only[f.Name]) {
+				locked = append(locked, f.Name)
+			}
+		}
+
+		if len(locked) > 0 {
+			items = append(items, &LockedItem{
+				Fields:    locked,
+				RatingKey: m.RatingKey,
+				Title:     lockableTitle(m),
+				Type:      m.Type,
+			})
+		}
+	}
+
+	return items, nil
+}
+
+func lockableTitle(m libraryItem) string {
+	switch m.Type {
+	case "episode":
+		return fmt.Sprintf("%s (S%02dE%02d): %s", m.GrandparentTitle, m.ParentIndex, m.Index, m.Title)
+	case "season":
+		return fmt.Sprintf("%s (S%02d)", m.ParentTitle, m.Index)
+	default:
+		return m.Title
+	}
+}
+
+func (p *Plex) Unlock(ctx context.Context, libraryKey string, item *LockedItem) error {
+	query := url.Values{}
+
+	query.Set("type", plex.GetMediaTypeID(item.Type))
+	query.Set("id", item.RatingKey)
+
+	for _, f := range item.Fields {
+		query.Set(f+".locked", "0")
+	}
+
+	return p.request(ctx, "PUT", fmt.Sprintf("/library/sections/%s/all", libraryKey), query, nil)
+}
+
+func LockedItemsAscii(w io.Writer, items []*LockedItem) {
+	t := tablewriter.NewWriter(w)
+
+	t.SetHeader([]string{"Title", "Type", "Locked Fields"})
+
+	for _, i := range items {
+		t.Append([]string{
+			i.Title,
+			i.Type,
+			strings.Join(i.Fields, ", "),
+		})
+	}
+
+	t.Render()
+}
@@ -0,0 +1,110 @@
+package plex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// libraryItem is a movie, show, season or episode of a library listing, with
+// the fields needed to unlock or update its metadata.
+type libraryItem struct {
+	Art   string `json:"art"`
+	Field []struct {
+		Locked bool   `json:"locked"`
+		Name   string `json:"name"`
+	} `json:"Field"`
+	Genre []struct {
+		Tag string `json:"tag"`
+	} `json:"Genre"`
+	GrandparentTitle string  `json:"grandparentTitle"`
+	GUID             string  `json:"guid"`
+	Index            int64   `json:"index"`
+	ParentIndex      int64   `json:"parentIndex"`
+	ParentTitle      string  `json:"parentTitle"`
+	Rating           float64 `json:"rating"`
+	RatingKey        string  `json:"ratingKey"`
+	Summary          string  `json:"summary"`
+	Thumb            string  `json:"thumb"`
+	Title            string  `json:"title"`
+	Type             string  `json:"type"`
+	Year             int     `json:"year"`
+	Media            []struct {
+		Part []struct {
+			File string `json:"file"`
+		} `json:"Part"`
+	} `json:"Media"`
+}
+
+type libraryContainer struct {
+	MediaContainer struct {
+		Metadata []libraryItem `json:"Metadata"`
+	} `json:"MediaContainer"`
+}
+
+// walkLibrary lists every movie, show, season and episode of a library, each
+// show and season followed by its children.
+func (p *Plex) walkLibrary(ctx context.Context, libraryKey string) ([]libraryItem, error) {
+	t := newTraversal(ctx, p)
+
+	defer t.cancel()
+
+	var lc libraryContainer
+
+	if err := t.fetch(fmt.Sprintf("/library/sections/%s/all", libraryKey), &lc); err != nil {
+		return nil, err
+	}
+
+	items := t.walk(lc.MediaContainer.Metadata)
+
+	if err := t.error(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (t *traversal) walk(metadata []libraryItem) []libraryItem {
+	results := make([][]libraryItem, len(metadata))
+
+	var wg sync.WaitGroup
+
+	for k, m := range metadata {
+		results[k] = []libraryItem{m}
+
+		switch m.Type {
+		case "episode", "movie":
+			t.report(0, 1)
+		case "season", "show":
+			wg.Add(1)
+
+			go func(k int, ratingKey string) {
+				defer wg.Done()
+
+				var sub libraryContainer
+
+				if err := t.fetch(fmt.Sprintf("/library/metadata/%s/children", ratingKey), &sub); err != nil {
+					t.fail(err)
+
+					return
+				}
+
+				t.report(1, 0)
+
+				results[k] = append(results[k], t.walk(sub.MediaContainer.Metadata)...)
+			}(k, m.RatingKey)
+		default:
+			t.fail(fmt.Errorf("unsupported type \"%s\"", m.Type))
+		}
+	}
+
+	wg.Wait()
+
+	items := make([]libraryItem, 0)
+
+	for _, res := range results {
+		items = append(items, res...)
+	}
+
+	return items
+}
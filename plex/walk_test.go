@@ -0,0 +1,99 @@
+package plex
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLockedItemsWalksShows(t *testing.T) {
+	s := fakeServer(t, map[string]string{
+		"/": identityResponse,
+		"/library/sections/2/all": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "1", "type": "show", "title": "Show", "Field": [{"name": "title", "locked": true}]},
+			{"ratingKey": "9", "type": "show", "title": "Other"}
+		]}}`,
+		"/library/metadata/1/children": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "2", "type": "season", "index": 1, "parentTitle": "Show"},
+			{"ratingKey": "3", "type": "season", "index": 2, "parentTitle": "Show", "Field": [{"name": "thumb", "locked": true}]}
+		]}}`,
+		"/library/metadata/2/children": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "4", "type": "episode", "index": 1, "parentIndex": 1, "grandparentTitle": "Show", "title": "Pilot", "Field": [{"name": "summary", "locked": true}, {"name": "title", "locked": true}]}
+		]}}`,
+		"/library/metadata/3/children": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "5", "type": "episode", "index": 1, "parentIndex": 2, "grandparentTitle": "Show", "title": "Return", "Field": [{"name": "title", "locked": false}]}
+		]}}`,
+		"/library/metadata/9/children": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "10", "type": "season", "index": 1, "parentTitle": "Other", "Field": [{"name": "title", "locked": true}]}
+		]}}`,
+		"/library/metadata/10/children": `{"MediaContainer": {"Metadata": []}}`,
+	})
+
+	defer s.Close()
+
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := p.LockedItems(context.Background(), "2", nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*LockedItem{
+		{Fields: []string{"title"}, RatingKey: "1", Title: "Show", Type: "show"},
+		{Fields: []string{"summary", "title"}, RatingKey: "4", Title: "Show (S01E01): Pilot", Type: "episode"},
+		{Fields: []string{"thumb"}, RatingKey: "3", Title: "Show (S02)", Type: "season"},
+		{Fields: []string{"title"}, RatingKey: "10", Title: "Other (S01)", Type: "season"},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("unexpected locked items:")
+
+		for _, i := range items {
+			t.Errorf("  %+v", i)
+		}
+	}
+
+	items, err = p.LockedItems(context.Background(), "2", []string{"poster"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(items) != 1 || items[0].RatingKey != "3" {
+		t.Errorf("expected only the poster of season 2 to be locked, got %+v", items)
+	}
+}
+
+func TestMetadataItemsFailsOnUnsupportedTypes(t *testing.T) {
+	s := fakeServer(t, map[string]string{
+		"/": identityResponse,
+		"/library/sections/3/all": `{"MediaContainer": {"Metadata": [
+			{"ratingKey": "1", "type": "artist", "title": "Artist"}
+		]}}`,
+	})
+
+	defer s.Close()
+
+	p, err := New("token")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.UseURL(s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.MetadataItems(context.Background(), "3"); err == nil || err.Error() != "unsupported type \"artist\"" {
+		t.Errorf("expected an unsupported type error, got %v", err)
+	}
+}